@@ -1,6 +1,7 @@
 package checkup
 
 import (
+	"context"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -8,6 +9,10 @@ import (
 	"github.com/aws/aws-sdk-go/service/ec2"
 )
 
+func init() {
+	RegisterChecker("backup:ami", func() Checker { return BackupAMIChecker{} })
+}
+
 // BackupAMIChecker implements a Checker for ami.
 type BackupAMIChecker struct {
 	// Name is the name of the endpoint.
@@ -25,14 +30,31 @@ type BackupAMIChecker struct {
 	MinAgeThreshold string `json:"min_age_threshold,omitempty"`
 
 	ec2Service *ec2.EC2
+
+	// CheckerOptions are the settings common to all checkers.
+	CheckerOptions
+}
+
+// Title returns the endpoint name of c.
+func (c BackupAMIChecker) Title() string {
+	return c.Name
 }
 
 // Check performs checks using c according to its configuration.
 // An error is only returned if there is a configuration error.
 func (c BackupAMIChecker) Check() (Result, error) {
+	return c.CheckContext(context.Background())
+}
+
+// CheckContext performs checks like Check, aborting the
+// AWS API request once ctx is done.
+func (c BackupAMIChecker) CheckContext(ctx context.Context) (Result, error) {
 	if c.Region == "" {
 		c.Region = "eu-west-1"
 	}
+	if err := c.validate(); err != nil {
+		return Result{}, err
+	}
 	var oldThreshold time.Duration
 	if c.MinAgeThreshold == "" {
 		c.MinAgeThreshold = "36h"
@@ -53,8 +75,12 @@ func (c BackupAMIChecker) Check() (Result, error) {
 			},
 		},
 	}
-	resp, err := c.ec2Service.DescribeImages(input)
+	resp, err := c.ec2Service.DescribeImagesWithContext(ctx, input)
 	if err != nil {
+		if ctx.Err() != nil {
+			result.Times[0].Error = attemptError(ctx, err)
+			return c.conclude(result), nil
+		}
 		return result, err
 	}
 	var lastAmi *ec2.Image = nil
@@ -71,6 +97,12 @@ func (c BackupAMIChecker) Check() (Result, error) {
 	if lastAmi == nil {
 		result.Times[0].Error = "no backup"
 	} else {
+		result.Details = map[string]interface{}{
+			"image_id":   aws.StringValue(lastAmi.ImageId),
+			"image_name": aws.StringValue(lastAmi.Name),
+			"created":    formatTime(lastAmiCreationDate),
+			"age":        formatAge(lastAmiCreationDate),
+		}
 		if lastAmiCreationDate.Before(time.Now().Add(-1 * oldThreshold)) {
 			result.Times[0].Error = "no recent backup"
 		}
@@ -82,12 +114,12 @@ func (c BackupAMIChecker) Check() (Result, error) {
 // conclude takes the data in result from the attempts and
 // computes remaining values needed to fill out the result.
 func (c BackupAMIChecker) conclude(result Result) Result {
+	stats := result.ComputeStats()
+
 	// Check errors (down)
-	for i := range result.Times {
-		if result.Times[i].Error != "" {
-			result.Down = true
-			return result
-		}
+	if c.down(stats) {
+		result.Down = true
+		return result
 	}
 
 	result.Healthy = true
@@ -1,6 +1,7 @@
 package checkup
 
 import (
+	"context"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -8,6 +9,10 @@ import (
 	"github.com/aws/aws-sdk-go/service/rds"
 )
 
+func init() {
+	RegisterChecker("backup:rds", func() Checker { return BackupRDSChecker{} })
+}
+
 // BackupRDSChecker implements a Checker for ami.
 type BackupRDSChecker struct {
 	// Name is the name of the endpoint.
@@ -25,14 +30,31 @@ type BackupRDSChecker struct {
 	MinAgeThreshold string `json:"min_age_threshold,omitempty"`
 
 	rdsService *rds.RDS
+
+	// CheckerOptions are the settings common to all checkers.
+	CheckerOptions
+}
+
+// Title returns the endpoint name of c.
+func (c BackupRDSChecker) Title() string {
+	return c.Name
 }
 
 // Check performs checks using c according to its configuration.
 // An error is only returned if there is a configuration error.
 func (c BackupRDSChecker) Check() (Result, error) {
+	return c.CheckContext(context.Background())
+}
+
+// CheckContext performs checks like Check, aborting the
+// AWS API request once ctx is done.
+func (c BackupRDSChecker) CheckContext(ctx context.Context) (Result, error) {
 	if c.Region == "" {
 		c.Region = "eu-west-1"
 	}
+	if err := c.validate(); err != nil {
+		return Result{}, err
+	}
 	var oldThreshold time.Duration
 	if c.MinAgeThreshold == "" {
 		c.MinAgeThreshold = "36h"
@@ -50,8 +72,12 @@ func (c BackupRDSChecker) Check() (Result, error) {
 		IncludePublic:        aws.Bool(false),
 		IncludeShared:        aws.Bool(false),
 	}
-	resp, err := c.rdsService.DescribeDBSnapshots(input)
+	resp, err := c.rdsService.DescribeDBSnapshotsWithContext(ctx, input)
 	if err != nil {
+		if ctx.Err() != nil {
+			result.Times[0].Error = attemptError(ctx, err)
+			return c.conclude(result), nil
+		}
 		return result, err
 	}
 	var lastSnapshot *rds.DBSnapshot = nil
@@ -65,6 +91,11 @@ func (c BackupRDSChecker) Check() (Result, error) {
 	if lastSnapshot == nil {
 		result.Times[0].Error = "no backup"
 	} else {
+		result.Details = map[string]interface{}{
+			"snapshot_id": aws.StringValue(lastSnapshot.DBSnapshotIdentifier),
+			"created":     formatTime(*lastSnapshot.SnapshotCreateTime),
+			"age":         formatAge(*lastSnapshot.SnapshotCreateTime),
+		}
 		if (*lastSnapshot.SnapshotCreateTime).Before(time.Now().Add(-1 * oldThreshold)) {
 			result.Times[0].Error = "no recent backup"
 		}
@@ -76,12 +107,12 @@ func (c BackupRDSChecker) Check() (Result, error) {
 // conclude takes the data in result from the attempts and
 // computes remaining values needed to fill out the result.
 func (c BackupRDSChecker) conclude(result Result) Result {
+	stats := result.ComputeStats()
+
 	// Check errors (down)
-	for i := range result.Times {
-		if result.Times[i].Error != "" {
-			result.Down = true
-			return result
-		}
+	if c.down(stats) {
+		result.Down = true
+		return result
 	}
 
 	result.Healthy = true
@@ -1,6 +1,7 @@
 package checkup
 
 import (
+	"context"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -8,6 +9,10 @@ import (
 	"github.com/aws/aws-sdk-go/service/s3"
 )
 
+func init() {
+	RegisterChecker("backup:s3", func() Checker { return BackupS3Checker{} })
+}
+
 // BackupS3Checker implements a Checker for S3.
 type BackupS3Checker struct {
 	// Name is the name of the endpoint.
@@ -32,14 +37,31 @@ type BackupS3Checker struct {
 	MinSizeThreshold int64 `json:"min_size_threshold,omitempty"`
 
 	s3Service *s3.S3
+
+	// CheckerOptions are the settings common to all checkers.
+	CheckerOptions
+}
+
+// Title returns the endpoint name of c.
+func (c BackupS3Checker) Title() string {
+	return c.Name
 }
 
 // Check performs checks using c according to its configuration.
 // An error is only returned if there is a configuration error.
 func (c BackupS3Checker) Check() (Result, error) {
+	return c.CheckContext(context.Background())
+}
+
+// CheckContext performs checks like Check, aborting the
+// AWS API request once ctx is done.
+func (c BackupS3Checker) CheckContext(ctx context.Context) (Result, error) {
 	if c.Region == "" {
 		c.Region = "eu-west-1"
 	}
+	if err := c.validate(); err != nil {
+		return Result{}, err
+	}
 	var oldThreshold time.Duration
 	if c.MinAgeThreshold == "" {
 		c.MinAgeThreshold = "36h"
@@ -59,8 +81,12 @@ func (c BackupS3Checker) Check() (Result, error) {
 		Bucket: aws.String(c.BucketName),
 		Prefix: aws.String(c.BucketPrefix),
 	}
-	resp, err := c.s3Service.ListObjectsV2(input)
+	resp, err := c.s3Service.ListObjectsV2WithContext(ctx, input)
 	if err != nil {
+		if ctx.Err() != nil {
+			result.Times[0].Error = attemptError(ctx, err)
+			return c.conclude(result), nil
+		}
 		return result, err
 	}
 	var lastItem *s3.Object = nil
@@ -69,9 +95,14 @@ func (c BackupS3Checker) Check() (Result, error) {
 			lastItem = item
 		}
 	}
+	result.Details = map[string]interface{}{"bucket": c.BucketName}
 	if lastItem == nil {
 		result.Times[0].Error = "no backup"
 	} else {
+		result.Details["key"] = *lastItem.Key
+		result.Details["size"] = *lastItem.Size
+		result.Details["last_modified"] = formatTime(*lastItem.LastModified)
+		result.Details["age"] = formatAge(*lastItem.LastModified)
 		if (*lastItem.LastModified).Before(time.Now().Add(-1 * oldThreshold)) {
 			result.Times[0].Error = "no recent backup"
 		}
@@ -86,12 +117,12 @@ func (c BackupS3Checker) Check() (Result, error) {
 // conclude takes the data in result from the attempts and
 // computes remaining values needed to fill out the result.
 func (c BackupS3Checker) conclude(result Result) Result {
+	stats := result.ComputeStats()
+
 	// Check errors (down)
-	for i := range result.Times {
-		if result.Times[i].Error != "" {
-			result.Down = true
-			return result
-		}
+	if c.down(stats) {
+		result.Down = true
+		return result
 	}
 
 	result.Healthy = true
@@ -5,9 +5,12 @@ package checkup
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
+	"math"
+	"os"
 	"sort"
 	"strings"
 	"sync"
@@ -28,6 +31,39 @@ type Checkup struct {
 	// DefaultConcurrentChecks.
 	ConcurrentChecks int `json:"concurrent_checks,omitempty"`
 
+	// RunTimeout is the maximum duration of a whole run of
+	// checks. Checkers that are still running when it elapses
+	// are concluded as down. If zero, runs have no deadline
+	// unless one is imposed by CheckAndStoreEvery.
+	RunTimeout time.Duration `json:"run_timeout,omitempty"`
+
+	// Maintenance lists windows of planned work during which
+	// results are reported with the Maintenance status. They
+	// apply to all checkers, unless they list endpoints. The
+	// maintenance scheduled in Storage applies as well; see
+	// ScheduleMaintenance.
+	Maintenance []MaintenanceWindow `json:"maintenance,omitempty"`
+
+	// Node identifies the instance of checkup performing
+	// the checks, when several of them check the same
+	// endpoints. It is recorded in the results.
+	Node string `json:"node,omitempty"`
+
+	// Location is where Node performs the checks from, such
+	// as a region or a city. It is recorded in the results.
+	Location string `json:"location,omitempty"`
+
+	// StateRules determine how the status reported for
+	// endpoints follows their observed status across runs.
+	// Checkers can override them.
+	StateRules
+
+	// StateFile is the path of a file in which to keep the
+	// state of endpoints across runs, when StateRules are
+	// used. If empty, the state is saved with the results
+	// and restored from Storage if it is a StorageReader.
+	StateFile string `json:"state_file,omitempty"`
+
 	// Timestamp is the timestamp to force for all checks.
 	// Useful if wanting to perform distributed check
 	// "at the same time" even if they might actually
@@ -45,12 +81,54 @@ type Checkup struct {
 	// completed. Notifier may evaluate and choose to
 	// send a notification of potential problems.
 	Notifier Notifier `json:"notifier,omitempty"`
+
+	// Notifiers are more notifiers that are passed the
+	// results like Notifier, such as to alert different
+	// people about different endpoints. Wrap them in a
+	// RoutedNotifier to choose their results. A notifier
+	// that fails doesn't stop the others.
+	Notifiers []Notifier `json:"notifiers,omitempty"`
+
+	// NotifierStateFile is the path of a file in which
+	// notifiers keep the alerts they sent, so that they
+	// don't alert again, or miss a recovery, once
+	// restarted. If not set, alerts are kept in Storage if
+	// it is a NotifierState (such as SQL) or else a
+	// DocumentStorage (such as FS), or in memory.
+	NotifierStateFile string `json:"notifier_state_file,omitempty"`
+
+	// NotifierState, if set, is where notifiers keep the
+	// alerts they sent, instead of NotifierStateFile.
+	NotifierState NotifierState `json:"-"`
+
+	// Metrics, if set, records the results and the activity
+	// of c, for Prometheus.
+	Metrics *Metrics `json:"-"`
+
+	// states is the state of endpoints kept across runs.
+	states *states
+
+	// stateless is set for runs that must leave the state
+	// of endpoints untouched, such as Nagios probes.
+	stateless bool
+
+	// maintenance, if set, keeps the scheduled maintenance
+	// across runs.
+	maintenance *maintenanceCache
 }
 
 // Check performs the health checks. An error is only
 // returned in the case of a misconfiguration or if
 // any one of the Checkers returns an error.
 func (c Checkup) Check() ([]Result, error) {
+	return c.CheckContext(context.Background())
+}
+
+// CheckContext performs the health checks like Check, but
+// gives up on any checker that is still running once ctx is
+// done or c.RunTimeout has elapsed. Such checkers produce a
+// down result instead of blocking the whole run.
+func (c Checkup) CheckContext(ctx context.Context) ([]Result, error) {
 	if c.ConcurrentChecks == 0 {
 		c.ConcurrentChecks = DefaultConcurrentChecks
 	}
@@ -58,76 +136,189 @@ func (c Checkup) Check() ([]Result, error) {
 		return nil, fmt.Errorf("invalid value for ConcurrentChecks: %d (must be set > 0)",
 			c.ConcurrentChecks)
 	}
+	if c.RunTimeout < 0 {
+		return nil, fmt.Errorf("invalid value for RunTimeout: %s (must be >= 0)", c.RunTimeout)
+	}
+	// notifiers aren't bound by c.RunTimeout
+	notifyCtx := ctx
+	if c.RunTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.RunTimeout)
+		defer cancel()
+	}
+	start := time.Now()
+	deps, _ := dependencyGraph(c.Checkers)
+	order, err := dependencyOrder(c.Checkers, deps)
+	if err != nil {
+		return nil, err
+	}
 
 	results := make([]Result, len(c.Checkers))
 	errs := make(Errors, len(c.Checkers))
 	throttle := make(chan struct{}, c.ConcurrentChecks)
 	wg := sync.WaitGroup{}
 
-	for i, checker := range c.Checkers {
-		throttle <- struct{}{}
-		wg.Add(1)
-		go func(i int, checker Checker) {
-			results[i], errs[i] = checker.Check()
-			<-throttle
-			wg.Done()
-		}(i, checker)
-	}
-	wg.Wait()
-
-	if !c.Timestamp.IsZero() {
-		for i := range results {
+	// checkers run after the checkers they depend on
+	for _, layer := range dependencyLayers(order, deps) {
+		for _, i := range layer {
+			throttle <- struct{}{}
+			wg.Add(1)
+			go func(i int, checker Checker) {
+				results[i], errs[i] = checkContext(ctx, checker)
+				<-throttle
+				wg.Done()
+			}(i, c.Checkers[i])
+		}
+		wg.Wait()
+	}
+
+	for i := range results {
+		if !c.Timestamp.IsZero() {
 			results[i].Timestamp = c.Timestamp.UTC().UnixNano()
 		}
+		results[i].Type, _ = checkerTypes.name(c.Checkers[i])
+		results[i].Tags = optionsOf(c.Checkers[i]).Tags
+		results[i].Node = c.Node
+		results[i].Location = c.Location
 	}
 
 	if !errs.Empty() {
 		return results, errs
 	}
 
-	if c.Notifier != nil {
-		err := c.Notifier.Notify(results)
+	scheduled := c.maintenance.scheduledWindows(c)
+	for i, checker := range c.Checkers {
+		opts := optionsOf(checker)
+		loc, err := opts.location()
+		if err != nil {
+			return results, err
+		}
+		windows := append(c.Maintenance[:len(c.Maintenance):len(c.Maintenance)], opts.Maintenance...)
+		windows = append(windows, scheduled...)
+		results[i], err = applyMaintenance(results[i], windows, loc)
 		if err != nil {
 			return results, err
 		}
 	}
 
+	if err := c.evaluateStates(results); err != nil {
+		return results, err
+	}
+
+	if err := markUnreachable(c.Checkers, results, nil); err != nil {
+		return results, err
+	}
+
+	c.Metrics.observeRun(time.Since(start))
+	c.Metrics.Observe(results)
+
+	if err := c.notify(notifyCtx, results); err != nil {
+		return results, err
+	}
+
 	return results, nil
 }
 
+// checkContext runs checker until it completes or ctx is done.
+// ContextCheckers are trusted to return promptly once ctx is
+// done; other checkers are abandoned at that point.
+func checkContext(ctx context.Context, checker Checker) (Result, error) {
+	if cc, ok := checker.(ContextChecker); ok {
+		result, err := cc.CheckContext(ctx)
+		if err != nil && ctx.Err() != nil {
+			return timedOut(result, ctx), nil
+		}
+		return result, err
+	}
+
+	type checkResult struct {
+		result Result
+		err    error
+	}
+	done := make(chan checkResult, 1)
+	go func() {
+		result, err := checker.Check()
+		done <- checkResult{result, err}
+	}()
+
+	select {
+	case cr := <-done:
+		return cr.result, cr.err
+	case <-ctx.Done():
+		result := Result{Timestamp: Timestamp()}
+		result.Notice = fmt.Sprintf("%T did not complete in time", checker)
+		return timedOut(result, ctx), nil
+	}
+}
+
+// timedOut concludes result as down because ctx is done
+// before the check could complete.
+func timedOut(result Result, ctx context.Context) Result {
+	result.Times = append(result.Times, Attempt{Error: attemptError(ctx, ctx.Err())})
+	result.Healthy = false
+	result.Degraded = false
+	result.Down = true
+	return result
+}
+
+// attemptError returns the error to record for an attempt that
+// failed with err. If ctx is done, the attempt is reported as
+// timed out (or canceled) rather than with the lower-level error
+// that the cancellation caused.
+func attemptError(ctx context.Context, err error) string {
+	switch ctx.Err() {
+	case context.DeadlineExceeded:
+		return "timed out: run deadline exceeded"
+	case context.Canceled:
+		return "canceled"
+	}
+	return err.Error()
+}
+
+// sleep pauses for d or until ctx is done, whichever
+// happens first.
+func sleep(ctx context.Context, d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
 // CheckAndStore performs health checks and immediately
 // stores the results to the configured storage if there
 // were no errors. Checks are not performed if c.Storage
 // is nil. If c.Storage is also a Maintainer, Maintain()
-// will be called if Store() is successful.
+// will be called if Store() is successful. The results are
+// then notified, even if they couldn't be stored; errors of
+// the storage and of the notifiers are returned together.
 func (c Checkup) CheckAndStore() error {
 	if c.Storage == nil {
 		return fmt.Errorf("no storage mechanism defined")
 	}
-	results, err := c.Check()
+	results, err := c.WithoutNotifiers().Check()
 	if err != nil {
 		return err
 	}
-
-	err = c.Storage.Store(results)
-	if err != nil {
-		return err
-	}
-
-	if m, ok := c.Storage.(Maintainer); ok {
-		return m.Maintain()
-	}
-
-	return nil
+	return c.store(context.Background(), results)
 }
 
 // CheckAndStoreEvery calls CheckAndStore every interval. It returns
 // the ticker that it's using so you can stop it when you don't want
 // it to run anymore. This function does NOT block (it runs the ticker
-// in a goroutine). Any errors are written to the standard logger. It
-// would not be wise to set an interval lower than the time it takes
-// to perform the checks.
+// in a goroutine). Any errors are written to the standard logger. If
+// c.RunTimeout is not set, each run is given interval as its deadline
+// so that a hung checker cannot delay the following runs.
+// Intervals set on individual checkers are ignored; use
+// CheckAndStoreScheduled to honor them.
 func (c Checkup) CheckAndStoreEvery(interval time.Duration) *time.Ticker {
+	if c.RunTimeout == 0 {
+		c.RunTimeout = interval
+	}
+	if c.states == nil {
+		c.states = newStates()
+	}
 	if err := c.CheckAndStore(); err != nil {
 		log.Println(err)
 	}
@@ -148,11 +339,25 @@ func (c Checkup) MarshalJSON() ([]byte, error) {
 	// Start with the fields of c that don't require special
 	// handling; unfortunately this has to mimic c's definition.
 	easy := struct {
-		ConcurrentChecks int       `json:"concurrent_checks,omitempty"`
-		Timestamp        time.Time `json:"timestamp,omitempty"`
+		ConcurrentChecks int                 `json:"concurrent_checks,omitempty"`
+		Node             string              `json:"node,omitempty"`
+		Location         string              `json:"location,omitempty"`
+		RunTimeout       time.Duration       `json:"run_timeout,omitempty"`
+		Maintenance      []MaintenanceWindow `json:"maintenance,omitempty"`
+		StateRules
+		StateFile         string    `json:"state_file,omitempty"`
+		NotifierStateFile string    `json:"notifier_state_file,omitempty"`
+		Timestamp         time.Time `json:"timestamp,omitempty"`
 	}{
-		ConcurrentChecks: c.ConcurrentChecks,
-		Timestamp:        c.Timestamp,
+		ConcurrentChecks:  c.ConcurrentChecks,
+		Node:              c.Node,
+		Location:          c.Location,
+		RunTimeout:        c.RunTimeout,
+		Maintenance:       c.Maintenance,
+		StateRules:        c.StateRules,
+		StateFile:         c.StateFile,
+		NotifierStateFile: c.NotifierStateFile,
+		Timestamp:         c.Timestamp,
 	}
 	result, err := json.Marshal(easy)
 	if err != nil {
@@ -172,30 +377,10 @@ func (c Checkup) MarshalJSON() ([]byte, error) {
 	if len(c.Checkers) > 0 {
 		var checkers [][]byte
 		for _, ch := range c.Checkers {
-			chb, err := json.Marshal(ch)
+			chb, err := checkerTypes.encode("type", ch)
 			if err != nil {
 				return result, err
 			}
-			var typeName string
-			switch ch.(type) {
-			case BackupS3Checker:
-				typeName = "backup:s3"
-			case BackupAMIChecker:
-				typeName = "backup:ami"
-			case BackupRDSChecker:
-				typeName = "backup:rds"
-			case HTTPChecker:
-				typeName = "http"
-			case TCPChecker:
-				typeName = "tcp"
-			case DNSChecker:
-				typeName = "dns"
-			case TLSChecker:
-				typeName = "tls"
-			default:
-				return result, fmt.Errorf("unknown Checker type")
-			}
-			chb = []byte(fmt.Sprintf(`{"type":"%s",%s`, typeName, string(chb[1:])))
 			checkers = append(checkers, chb)
 		}
 
@@ -207,68 +392,66 @@ func (c Checkup) MarshalJSON() ([]byte, error) {
 
 	// Storage
 	if c.Storage != nil {
-		sb, err := json.Marshal(c.Storage)
+		sb, err := storageTypes.encode("provider", c.Storage)
 		if err != nil {
 			return result, err
 		}
-		var providerName string
-		switch c.Storage.(type) {
-		case *GitHub:
-			providerName = "github"
-		case FS:
-			providerName = "fs"
-		case SQL:
-			providerName = "sql"
-		default:
-			return result, fmt.Errorf("unknown Storage type: %T", c.Storage)
-		}
-		sb = []byte(fmt.Sprintf(`{"provider":"%s",%s`, providerName, string(sb[1:])))
 		wrap("storage", sb)
 	}
 
 	// Notifier
 	if c.Notifier != nil {
-		nb, err := json.Marshal(c.Notifier)
+		nb, err := encodeNotifier(c.Notifier)
 		if err != nil {
 			return result, err
 		}
-		var notifierName string
-		switch c.Notifier.(type) {
-		case Slack:
-			notifierName = "slack"
-		default:
-			return result, fmt.Errorf("unknown Notifier type")
-		}
-		nb = []byte(fmt.Sprintf(`{"name":"%s",%s`, notifierName, string(nb[1:])))
 		wrap("notifier", nb)
 	}
 
+	// Notifiers
+	if len(c.Notifiers) > 0 {
+		var notifiers [][]byte
+		for _, n := range c.Notifiers {
+			nb, err := encodeNotifier(n)
+			if err != nil {
+				return result, err
+			}
+			notifiers = append(notifiers, nb)
+		}
+		allNotifiers := append([]byte{'['}, bytes.Join(notifiers, []byte(","))...)
+		allNotifiers = append(allNotifiers, ']')
+		wrap("notifiers", allNotifiers)
+	}
+
 	return result, nil
 }
 
 // UnmarshalJSON unmarshales b into c. To succeed, it
 // requires type information for the interface values.
 func (c *Checkup) UnmarshalJSON(b []byte) error {
-	// Unmarshal as much of b as we can; this requires
-	// a type that doesn't implement json.Unmarshaler,
-	// hence the conversion. We also know that the
-	// interface types will ultimately cause an error,
-	// but we can ignore it because we handle it below.
-	type checkup2 *Checkup
-	json.Unmarshal(b, checkup2(c))
-	c.Checkers = []Checker{} // clean the slate
-
-	// Begin unmarshaling interface values by
-	// collecting the raw JSON
+	// Unmarshal the plain fields of b into c; this requires
+	// a type that doesn't implement json.Unmarshaler, hence
+	// the conversion. The interface values are shadowed so
+	// that their raw JSON is collected instead, to be
+	// unmarshaled below with the help of type information.
+	type checkup2 Checkup
 	raw := struct {
-		Checkers []json.RawMessage `json:"checkers"`
-		Storage  json.RawMessage   `json:"storage"`
-		Notifier json.RawMessage   `json:"notifier"`
-	}{}
-	err := json.Unmarshal([]byte(b), &raw)
+		*checkup2
+		Checkers  []json.RawMessage `json:"checkers"`
+		Storage   json.RawMessage   `json:"storage"`
+		Notifier  json.RawMessage   `json:"notifier"`
+		Notifiers []json.RawMessage `json:"notifiers"`
+	}{checkup2: (*checkup2)(c)}
+	err := json.Unmarshal(b, &raw)
 	if err != nil {
 		return err
 	}
+	c.Checkers = []Checker{} // clean the slate
+	for _, w := range c.Maintenance {
+		if err := w.validate(); err != nil {
+			return err
+		}
+	}
 
 	// Then collect the concrete type information
 	types := struct {
@@ -278,124 +461,214 @@ func (c *Checkup) UnmarshalJSON(b []byte) error {
 		Storage struct {
 			Provider string `json:"provider"`
 		}
-		Notifier struct {
-			Name     string `json:"name"`
-			Username string `json:"username"`
-			Channel  string `json:"channel"`
-			Webhook  string `json:"webhook"`
-		}
 	}{}
-	err = json.Unmarshal([]byte(b), &types)
+	err = json.Unmarshal(b, &types)
 	if err != nil {
 		return err
 	}
 
-	// Finally, we unmarshal the remaining values using type
-	// assertions with the help of the type information
+	// Finally, we unmarshal the remaining values into the
+	// types registered for the type information
 	for i, t := range types.Checkers {
-		switch t.Type {
-		case "backup:s3":
-			var checker BackupS3Checker
-			err = json.Unmarshal(raw.Checkers[i], &checker)
-			if err != nil {
-				return err
-			}
-			c.Checkers = append(c.Checkers, checker)
-		case "backup:ami":
-			var checker BackupAMIChecker
-			err = json.Unmarshal(raw.Checkers[i], &checker)
-			if err != nil {
-				return err
-			}
-			c.Checkers = append(c.Checkers, checker)
-		case "backup:rds":
-			var checker BackupRDSChecker
-			err = json.Unmarshal(raw.Checkers[i], &checker)
-			if err != nil {
-				return err
-			}
-			c.Checkers = append(c.Checkers, checker)
-		case "http":
-			var checker HTTPChecker
-			err = json.Unmarshal(raw.Checkers[i], &checker)
-			if err != nil {
-				return err
-			}
-			c.Checkers = append(c.Checkers, checker)
-		case "tcp":
-			var checker TCPChecker
-			err = json.Unmarshal(raw.Checkers[i], &checker)
-			if err != nil {
-				return err
-			}
-			c.Checkers = append(c.Checkers, checker)
-		case "dns":
-			var checker DNSChecker
-			err = json.Unmarshal(raw.Checkers[i], &checker)
-			if err != nil {
-				return err
-			}
-			c.Checkers = append(c.Checkers, checker)
-		case "tls":
-			var checker TLSChecker
-			err = json.Unmarshal(raw.Checkers[i], &checker)
-			if err != nil {
-				return err
-			}
-			c.Checkers = append(c.Checkers, checker)
-		default:
-			return fmt.Errorf("%s: unknown Checker type", t.Type)
+		checker, err := checkerTypes.decode(t.Type, raw.Checkers[i])
+		if err != nil {
+			return err
 		}
+		if err := optionsOf(checker.(Checker)).validate(); err != nil {
+			return fmt.Errorf("checker %d: %v", i, err)
+		}
+		c.Checkers = append(c.Checkers, checker.(Checker))
+	}
+	if err := checkDependencies(c.Checkers); err != nil {
+		return err
 	}
 	if raw.Storage != nil {
-		switch types.Storage.Provider {
-		case "fs":
-			var storage FS
-			err = json.Unmarshal(raw.Storage, &storage)
-			if err != nil {
-				return err
-			}
-			c.Storage = storage
-		case "github":
-			storage := &GitHub{}
-			err = json.Unmarshal(raw.Storage, storage)
-			if err != nil {
-				return err
-			}
-			c.Storage = storage
-		case "sql":
-			var storage SQL
-			err = json.Unmarshal(raw.Storage, &storage)
-			if err != nil {
-				return err
-			}
-			c.Storage = storage
-		default:
-			return fmt.Errorf("%s: unknown Storage type", types.Storage.Provider)
+		storage, err := storageTypes.decode(types.Storage.Provider, raw.Storage)
+		if err != nil {
+			return err
 		}
+		c.Storage = storage.(Storage)
 	}
 	if raw.Notifier != nil {
-		switch types.Notifier.Name {
-		case "slack":
-			var notifier Slack
-			err = json.Unmarshal(raw.Notifier, &notifier)
-			if err != nil {
-				return err
-			}
-			c.Notifier = notifier
-		default:
-			return fmt.Errorf("%s: unknown Notifier type", types.Notifier.Name)
+		notifier, err := decodeNotifier(raw.Notifier)
+		if err != nil {
+			return err
 		}
+		c.Notifier = notifier
+	}
+	c.Notifiers = nil
+	for i, nb := range raw.Notifiers {
+		notifier, err := decodeNotifier(nb)
+		if err != nil {
+			return fmt.Errorf("notifier %d: %v", i, err)
+		}
+		c.Notifiers = append(c.Notifiers, notifier)
 	}
 
 	return nil
 }
 
+// encodeNotifier marshals n like notifierTypes.encode, with
+// the route of n if it is a RoutedNotifier.
+func encodeNotifier(n Notifier) ([]byte, error) {
+	routed, ok := n.(RoutedNotifier)
+	if !ok {
+		return notifierTypes.encode("name", n)
+	}
+	nb, err := notifierTypes.encode("name", routed.Notifier)
+	if err != nil {
+		return nil, err
+	}
+	rb, err := json.Marshal(routed.Route)
+	if err != nil {
+		return nil, err
+	}
+	return append(append(append(nb[:len(nb)-1:len(nb)-1], `,"route":`...), rb...), '}'), nil
+}
+
+// decodeNotifier unmarshals b into the notifier type named
+// by its "name", wrapped in a RoutedNotifier if b has a
+// "route".
+func decodeNotifier(b []byte) (Notifier, error) {
+	var probe struct {
+		Name  string `json:"name"`
+		Route *Route `json:"route"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return nil, err
+	}
+	notifier, err := notifierTypes.decode(probe.Name, b)
+	if err != nil {
+		return nil, err
+	}
+	if v, ok := notifier.(interface{ validate() error }); ok {
+		if err := v.validate(); err != nil {
+			return nil, err
+		}
+	}
+	if probe.Route == nil {
+		return notifier.(Notifier), nil
+	}
+	if err := probe.Route.validate(); err != nil {
+		return nil, err
+	}
+	return RoutedNotifier{Notifier: notifier.(Notifier), Route: *probe.Route}, nil
+}
+
 // Checker can create a Result.
 type Checker interface {
 	Check() (Result, error)
 }
 
+// CheckerOptions holds the settings that every checker
+// supports, but that are applied by Checkup rather than by
+// the checker itself. Custom checkers can support them
+// too by embedding CheckerOptions.
+type CheckerOptions struct {
+	// Interval is how often the checker runs when checks
+	// are scheduled with CheckAndStoreScheduled. If zero,
+	// the default schedule is used.
+	Interval time.Duration `json:"interval,omitempty"`
+
+	// Schedule is a cron expression that determines when
+	// the checker runs when checks are scheduled with
+	// CheckAndStoreScheduled, as an alternative to Interval.
+	// It is evaluated in Timezone unless it starts with a
+	// CRON_TZ= prefix. See ParseSchedule.
+	Schedule string `json:"schedule,omitempty"`
+
+	// Timezone is the IANA name of the time zone in which
+	// the times of day in the checker's configuration are
+	// expressed, such as "America/New_York". Default is
+	// the local time zone, which is named by the TZ
+	// environment variable if it is set. The deprecated
+	// IgnoreTimes default to Europe/Paris without either.
+	Timezone string `json:"timezone,omitempty"`
+
+	// Maintenance lists windows of planned work on the
+	// checker's endpoint, in addition to the windows of
+	// the Checkup.
+	Maintenance []MaintenanceWindow `json:"maintenance,omitempty"`
+
+	// StateRules override the state rules of the Checkup
+	// for the checker's endpoint.
+	StateRules
+
+	// DependsOn lists the endpoint names of the checkers
+	// this checker depends on. When one of them is down, a
+	// failure of this checker is marked as unreachable due
+	// to it, and notifiers don't alert about it.
+	DependsOn []string `json:"depends_on,omitempty"`
+
+	// DownOn is the rule by which the checker's endpoint is
+	// concluded to be down, such as "failures > 1". Default
+	// (nil) is any failed attempt.
+	DownOn *Rule `json:"down_on,omitempty"`
+
+	// DegradeOn is the rule by which the checker's endpoint
+	// is concluded to be degraded, such as "p95 > 300ms".
+	// Default (nil) is the median round trip time exceeding
+	// the checker's threshold, if it has one.
+	DegradeOn *Rule `json:"degrade_on,omitempty"`
+
+	// Tags are labels of the checker's endpoint, such as
+	// "customer-facing", which are recorded in its results
+	// and by which notifiers can be routed. See Route.
+	Tags []string `json:"tags,omitempty"`
+}
+
+// location returns the time zone named by o.Timezone, or
+// the local time zone, which is named by the TZ environment
+// variable if it is set.
+func (o CheckerOptions) location() (*time.Location, error) {
+	if o.Timezone == "" {
+		return time.Local, nil
+	}
+	loc, err := time.LoadLocation(o.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone: %v", err)
+	}
+	return loc, nil
+}
+
+// legacyTimezone is the time zone in which IgnoreTimes are
+// evaluated when neither Timezone nor TZ is set, as they
+// always were.
+const legacyTimezone = "Europe/Paris"
+
+// ignoreLocation returns the time zone of the deprecated
+// IgnoreTimes of HTTP and TLS checkers: that of o.Timezone,
+// or else the one named by TZ, or else legacyTimezone.
+func (o CheckerOptions) ignoreLocation() (*time.Location, error) {
+	if o.Timezone == "" && os.Getenv("TZ") == "" {
+		return time.LoadLocation(legacyTimezone)
+	}
+	return o.location()
+}
+
+// Options returns o. It lets Checkup retrieve the options
+// of any checker that embeds CheckerOptions.
+func (o CheckerOptions) Options() CheckerOptions {
+	return o
+}
+
+// optionsOf returns the CheckerOptions of checker, or
+// the zero value if it doesn't have any.
+func optionsOf(checker Checker) CheckerOptions {
+	if o, ok := checker.(interface{ Options() CheckerOptions }); ok {
+		return o.Options()
+	}
+	return CheckerOptions{}
+}
+
+// ContextChecker is a Checker that can be canceled. Once
+// ctx is done, CheckContext should return promptly with
+// the attempts that did not complete marked as failed.
+type ContextChecker interface {
+	Checker
+	CheckContext(ctx context.Context) (Result, error)
+}
+
 // Storage can store results.
 type Storage interface {
 	Store([]Result) error
@@ -410,6 +683,27 @@ type StorageReader interface {
 	GetIndex() (map[string]int64, error)
 }
 
+// StorageWriter can store results in a check file of a
+// given name and timestamp, such as to copy the check
+// files of another storage. Storing into a check file
+// that already exists is not supported.
+type StorageWriter interface {
+	StoreCheckFile(name string, timestamp int64, results []Result) error
+}
+
+// DocumentStorage can store documents other than check
+// files next to them, such as incidents. Documents are
+// small JSON files with a fixed name, like "incidents.json",
+// that are replaced as a whole when stored.
+type DocumentStorage interface {
+	// FetchDocument returns the contents of the document
+	// name, or an error for which os.IsNotExist is true if
+	// there is no such document.
+	FetchDocument(name string) ([]byte, error)
+	// StoreDocument stores contents as the document name.
+	StoreDocument(name string, contents []byte) error
+}
+
 // Maintainer can maintain a store of results by
 // deleting old check files that are no longer
 // needed or performing other required tasks.
@@ -476,6 +770,56 @@ type Result struct {
 	Degraded bool `json:"degraded,omitempty"`
 	Down     bool `json:"down,omitempty"`
 
+	// Maintenance is set when the check occurred during
+	// planned work on the endpoint. The conclusion above
+	// is still reported as observed, but the status of
+	// the result is Maintenance.
+	Maintenance bool `json:"maintenance,omitempty"`
+
+	// Flapping is set when the observed status of the
+	// endpoint changed too often recently, according to
+	// the StateRules in effect. The status of the result
+	// is then Flapping, unless it is Maintenance.
+	Flapping bool `json:"flapping,omitempty"`
+
+	// Node and Location identify the checkup instance that
+	// produced this result, if configured.
+	Node     string `json:"node,omitempty"`
+	Location string `json:"location,omitempty"`
+
+	// Locations holds the status found by each location for
+	// a result merged from several locations, keyed by
+	// location (or node, if the location is not set). See
+	// MergeResults.
+	Locations map[string]StatusText `json:"locations,omitempty"`
+
+	// Details holds facts found by the checker, such as the
+	// status code of an HTTP response or the expiry of a
+	// certificate, keyed by name. Values are strings (times
+	// in RFC 3339 format and durations as in "1h30m0s"),
+	// numbers, booleans and lists of strings. Once stored
+	// and read back as JSON, numbers are float64 and lists
+	// are []interface{}; they print the same with %v.
+	Details map[string]interface{} `json:"details,omitempty"`
+
+	// Type is the name of the type of the checker that
+	// produced this result, such as "http".
+	Type string `json:"type,omitempty"`
+
+	// Tags are the tags of the checker that produced this
+	// result. See CheckerOptions.Tags.
+	Tags []string `json:"tags,omitempty"`
+
+	// UnreachableDueTo is the title of the endpoint this
+	// endpoint depends on which was down when this result
+	// failed. Notifiers should not alert about such results,
+	// as they are a consequence of the dependency being down.
+	UnreachableDueTo string `json:"unreachable_due_to,omitempty"`
+
+	// State is the state of the endpoint across runs after
+	// this result, if StateRules are in effect.
+	State *EndpointState `json:"state,omitempty"`
+
 	// Notice contains a description of some condition of this
 	// check that might have affected the result in some way.
 	// For example, that the median RTT is above the threshold.
@@ -486,11 +830,45 @@ type Result struct {
 	Message string `json:"message,omitempty"`
 }
 
-// ComputeStats computes basic statistics about r.
+// formatTime formats t for Result.Details.
+func formatTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// formatAge formats the time elapsed since t for
+// Result.Details.
+func formatAge(t time.Time) string {
+	return time.Since(t).Round(time.Second).String()
+}
+
+// location returns the location of r, or its node if
+// the location is not set.
+func (r Result) location() string {
+	if r.Location != "" {
+		return r.Location
+	}
+	return r.Node
+}
+
+// ComputeStats computes basic statistics about r. The
+// statistics of round trip times are computed over the
+// successful attempts of r. All of them are zero if r has
+// no attempts.
 func (r Result) ComputeStats() Stats {
 	var s Stats
+	if len(r.Times) == 0 {
+		return s
+	}
 
+	// round trip times are those of successful attempts, as
+	// failed ones may have ended before any round trip
+	var sorted Attempts
 	for _, a := range r.Times {
+		if a.Error != "" {
+			s.Failures++
+			continue
+		}
+		sorted = append(sorted, a)
 		s.Total += a.RTT
 		if a.RTT < s.Min || s.Min == 0 {
 			s.Min = a.RTT
@@ -499,8 +877,10 @@ func (r Result) ComputeStats() Stats {
 			s.Max = a.RTT
 		}
 	}
-	sorted := make(Attempts, len(r.Times))
-	copy(sorted, r.Times)
+	s.FailureRatio = float64(s.Failures) / float64(len(r.Times))
+	if len(sorted) == 0 {
+		return s
+	}
 	sort.Sort(sorted)
 
 	half := len(sorted) / 2
@@ -509,8 +889,18 @@ func (r Result) ComputeStats() Stats {
 	} else {
 		s.Median = sorted[half].RTT
 	}
+	s.P90 = sorted.percentile(90)
+	s.P95 = sorted.percentile(95)
+	s.P99 = sorted.percentile(99)
 
-	s.Mean = time.Duration(int64(s.Total) / int64(len(r.Times)))
+	s.Mean = time.Duration(int64(s.Total) / int64(len(sorted)))
+
+	var variance float64
+	for _, a := range sorted {
+		d := float64(a.RTT - s.Mean)
+		variance += d * d
+	}
+	s.StdDev = time.Duration(math.Sqrt(variance / float64(len(sorted))))
 
 	return s
 }
@@ -524,7 +914,12 @@ func (r Result) String() string {
 	s += fmt.Sprintf("        Min: %s\n", stats.Min)
 	s += fmt.Sprintf("     Median: %s\n", stats.Median)
 	s += fmt.Sprintf("       Mean: %s\n", stats.Mean)
+	s += fmt.Sprintf("        P95: %s\n", stats.P95)
+	s += fmt.Sprintf("     StdDev: %s\n", stats.StdDev)
 	s += fmt.Sprintf("        All: %v\n", r.Times)
+	if stats.Failures > 0 {
+		s += fmt.Sprintf("   Failures: %d of %d\n", stats.Failures, len(r.Times))
+	}
 	statusLine := fmt.Sprintf(" Assessment: %v\n", r.Status())
 	switch r.Status() {
 	case Healthy:
@@ -533,15 +928,43 @@ func (r Result) String() string {
 		statusLine = color.YellowString(statusLine)
 	case Down:
 		statusLine = color.RedString(statusLine)
+	case Flapping:
+		statusLine = color.MagentaString(statusLine)
+	case Maintenance:
+		statusLine = color.BlueString(statusLine)
 	}
 	s += statusLine
+	if loc := r.location(); loc != "" {
+		s += fmt.Sprintf("   Location: %s\n", loc)
+	}
+	if len(r.Locations) > 0 {
+		locations := make([]string, 0, len(r.Locations))
+		for loc, status := range r.Locations {
+			locations = append(locations, fmt.Sprintf("%s=%s", loc, status))
+		}
+		sort.Strings(locations)
+		s += fmt.Sprintf("  Locations: %s\n", strings.Join(locations, ", "))
+	}
+	if r.Notice != "" {
+		s += fmt.Sprintf("     Notice: %s\n", r.Notice)
+	}
+	if len(r.Details) > 0 {
+		s += "    Details:\n"
+		for _, key := range detailKeys(r) {
+			s += fmt.Sprintf("      %s: %v\n", key, r.Details[key])
+		}
+	}
 	return s
 }
 
 // Status returns a text representation of the overall status
 // indicated in r.
 func (r Result) Status() StatusText {
-	if r.Down {
+	if r.Maintenance {
+		return Maintenance
+	} else if r.Flapping {
+		return Flapping
+	} else if r.Down {
 		return Down
 	} else if r.Degraded {
 		return Degraded
@@ -563,32 +986,27 @@ type StatusText string
 // PriorityOver returns whether s has priority over other.
 // For example, a Down status has priority over Degraded.
 func (s StatusText) PriorityOver(other StatusText) bool {
-	if s == other {
-		return false
-	}
-	switch s {
-	case Down:
-		return true
-	case Degraded:
-		if other == Down {
-			return false
-		}
-		return true
-	case Healthy:
-		if other == Unknown {
-			return true
-		}
-		return false
-	}
-	return false
+	return statusPriority[s] > statusPriority[other]
+}
+
+// statusPriority ranks the statuses by priority.
+var statusPriority = map[StatusText]int{
+	Unknown:     0,
+	Healthy:     1,
+	Maintenance: 2,
+	Degraded:    3,
+	Flapping:    4,
+	Down:        5,
 }
 
 // Text representations for the status of a check.
 const (
-	Healthy  StatusText = "healthy"
-	Degraded StatusText = "degraded"
-	Down     StatusText = "down"
-	Unknown  StatusText = "unknown"
+	Healthy     StatusText = "healthy"
+	Degraded    StatusText = "degraded"
+	Down        StatusText = "down"
+	Flapping    StatusText = "flapping"
+	Maintenance StatusText = "maintenance"
+	Unknown     StatusText = "unknown"
 )
 
 // Attempt is an attempt to communicate with the endpoint.
@@ -604,6 +1022,16 @@ func (a Attempts) Len() int           { return len(a) }
 func (a Attempts) Swap(i, j int)      { a[i], a[j] = a[j], a[i] }
 func (a Attempts) Less(i, j int) bool { return a[i].RTT < a[j].RTT }
 
+// percentile returns the p-th percentile of the RTTs of a,
+// which must be sorted, using the nearest-rank method.
+func (a Attempts) percentile(p int) time.Duration {
+	rank := (p*len(a) + 99) / 100
+	if rank < 1 {
+		rank = 1
+	}
+	return a[rank-1].RTT
+}
+
 // Stats is a type that holds information about a Result,
 // especially its various Attempts.
 type Stats struct {
@@ -612,6 +1040,22 @@ type Stats struct {
 	Median time.Duration `json:"median,omitempty"`
 	Min    time.Duration `json:"min,omitempty"`
 	Max    time.Duration `json:"max,omitempty"`
+
+	// P90, P95 and P99 are percentiles of the round trip
+	// times: 90% of the successful attempts took at most
+	// P90, etc.
+	P90 time.Duration `json:"p90,omitempty"`
+	P95 time.Duration `json:"p95,omitempty"`
+	P99 time.Duration `json:"p99,omitempty"`
+
+	// StdDev is the standard deviation of the round trip
+	// times.
+	StdDev time.Duration `json:"stddev,omitempty"`
+
+	// Failures is how many attempts failed, and FailureRatio
+	// is the ratio of failed attempts to all attempts.
+	Failures     int     `json:"failures,omitempty"`
+	FailureRatio float64 `json:"failure_ratio,omitempty"`
 }
 
 // Errors is an error type that concatenates multiple errors.
@@ -2,8 +2,12 @@ package checkup
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 )
@@ -67,8 +71,10 @@ func TestCheckAndStoreEvery(t *testing.T) {
 	f := new(fake)
 	c := Checkup{Storage: f, Checkers: []Checker{f}}
 
+	// checks run right away, then at 50ms and 100ms; 170ms, as
+	// the test used to sleep, let a fourth check in at 150ms
 	ticker := c.CheckAndStoreEvery(50 * time.Millisecond)
-	time.Sleep(170 * time.Millisecond)
+	time.Sleep(120 * time.Millisecond)
 	ticker.Stop()
 
 	if got, want := f.checked, 3; got != want {
@@ -76,6 +82,46 @@ func TestCheckAndStoreEvery(t *testing.T) {
 	}
 }
 
+func TestCheckContextRunTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	c := Checkup{
+		Checkers: []Checker{
+			HTTPChecker{Name: "Hung", URL: srv.URL, Attempts: 2},
+			slow{delay: time.Second},
+			slow{},
+		},
+		RunTimeout: 100 * time.Millisecond,
+	}
+
+	start := time.Now()
+	results, err := c.CheckContext(context.Background())
+	if err != nil {
+		t.Fatalf("Didn't expect an error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("Expected run to be cut short by its deadline, took %s", elapsed)
+	}
+
+	for i, want := range []bool{true, true, false} {
+		if got := results[i].Down; got != want {
+			t.Errorf("Result %d: expected Down=%v, got %v", i, want, got)
+		}
+	}
+	if got, want := results[0].Title, "Hung"; got != want {
+		t.Errorf("Expected title '%s', got '%s'", want, got)
+	}
+	for i := 0; i < 2; i++ {
+		times := results[i].Times
+		if got, want := times[len(times)-1].Error, "timed out: run deadline exceeded"; got != want {
+			t.Errorf("Result %d: expected attempt error '%s', got '%s'", i, want, got)
+		}
+	}
+}
+
 func TestComputeStats(t *testing.T) {
 	s := Result{Times: []Attempt{
 		{RTT: 7 * time.Second},
@@ -101,6 +147,55 @@ func TestComputeStats(t *testing.T) {
 	if got, want := s.Max, 7*time.Second; got != want {
 		t.Errorf("Expected Max=%v, got %v", want, got)
 	}
+	if got, want := s.P90, 7*time.Second; got != want {
+		t.Errorf("Expected P90=%v, got %v", want, got)
+	}
+	if got, want := s.StdDev, 1414213562*time.Nanosecond; got != want {
+		t.Errorf("Expected StdDev=%v, got %v", want, got)
+	}
+
+	var times []Attempt
+	for i := 1; i <= 100; i++ {
+		a := Attempt{RTT: time.Duration(i) * time.Millisecond}
+		if i%4 == 0 {
+			a.Error = "failed"
+		}
+		times = append(times, a)
+	}
+	s = Result{Times: times}.ComputeStats()
+	if got, want := s.P90, 90*time.Millisecond; got != want {
+		t.Errorf("Expected P90=%v, got %v", want, got)
+	}
+	if got, want := s.P95, 95*time.Millisecond; got != want {
+		t.Errorf("Expected P95=%v, got %v", want, got)
+	}
+	if got, want := s.P99, 99*time.Millisecond; got != want {
+		t.Errorf("Expected P99=%v, got %v", want, got)
+	}
+	if got, want := s.Failures, 25; got != want {
+		t.Errorf("Expected Failures=%v, got %v", want, got)
+	}
+	if got, want := s.FailureRatio, 0.25; got != want {
+		t.Errorf("Expected FailureRatio=%v, got %v", want, got)
+	}
+
+	// failed attempts don't count in round trip times
+	s = Result{Times: []Attempt{{RTT: 100 * time.Millisecond}, {Error: "refused"}, {Error: "refused"}}}.ComputeStats()
+	if s.Min != 100*time.Millisecond || s.Median != 100*time.Millisecond || s.Mean != 100*time.Millisecond || s.P90 != 100*time.Millisecond {
+		t.Errorf("Expected round trip times of the successful attempt only, got %+v", s)
+	}
+	if got, want := s.Failures, 2; got != want {
+		t.Errorf("Expected Failures=%v, got %v", want, got)
+	}
+	s = Result{Times: []Attempt{{Error: "refused"}}}.ComputeStats()
+	if s != (Stats{Failures: 1, FailureRatio: 1}) {
+		t.Errorf("Expected only failures without successful attempts, got %+v", s)
+	}
+
+	// no attempts, no division by zero
+	if got, want := (Result{}).ComputeStats(), (Stats{}); got != want {
+		t.Errorf("Expected zero stats without attempts, got %+v", got)
+	}
 }
 
 func TestResultStatus(t *testing.T) {
@@ -124,6 +219,16 @@ func TestResultStatus(t *testing.T) {
 		t.Errorf("Expected status '%s' but got: '%s'", want, got)
 	}
 
+	r = Result{Down: true, Maintenance: true}
+	if got, want := r.Status(), Maintenance; got != want {
+		t.Errorf("Expected status '%s' but got: '%s'", want, got)
+	}
+
+	r = Result{Down: true, Flapping: true}
+	if got, want := r.Status(), Flapping; got != want {
+		t.Errorf("Expected status '%s' but got: '%s'", want, got)
+	}
+
 	// These are invalid states, but we need to test anyway in case a
 	// checker is buggy. We expect the worst of the enabled fields.
 	r = Result{Down: true, Degraded: true}
@@ -140,6 +245,35 @@ func TestResultStatus(t *testing.T) {
 	}
 }
 
+func TestResultStringDetails(t *testing.T) {
+	r := Result{Title: "Example", Healthy: true, Times: Attempts{{RTT: time.Second}}, Details: map[string]interface{}{
+		"status_code": 200,
+		"answers":     []string{"192.0.2.1"},
+	}}
+	s := r.String()
+	if !strings.Contains(s, "      answers: [192.0.2.1]\n      status_code: 200\n") {
+		t.Errorf("Expected details sorted by key in:\n%s", s)
+	}
+
+	var decoded Result
+	b, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := decoded.String(), s; got != want {
+		t.Errorf("Expected stored details to print the same, got:\n%s\nwant:\n%s", got, want)
+	}
+	if _, ok := decoded.Details["status_code"].(float64); !ok {
+		t.Errorf("Expected a stored number to read back as float64, got %T", decoded.Details["status_code"])
+	}
+	if _, ok := decoded.Details["answers"].([]interface{}); !ok {
+		t.Errorf("Expected a stored list to read back as []interface{}, got %T", decoded.Details["answers"])
+	}
+}
+
 func TestPriorityOver(t *testing.T) {
 	for i, test := range []struct {
 		status   StatusText
@@ -162,6 +296,21 @@ func TestPriorityOver(t *testing.T) {
 		{Unknown, Degraded, false},
 		{Unknown, Healthy, false},
 		{Unknown, Unknown, false},
+		{Maintenance, Down, false},
+		{Maintenance, Degraded, false},
+		{Maintenance, Healthy, true},
+		{Maintenance, Unknown, true},
+		{Maintenance, Maintenance, false},
+		{Down, Maintenance, true},
+		{Degraded, Maintenance, true},
+		{Healthy, Maintenance, false},
+		{Unknown, Maintenance, false},
+		{Flapping, Down, false},
+		{Flapping, Degraded, true},
+		{Flapping, Maintenance, true},
+		{Flapping, Healthy, true},
+		{Down, Flapping, true},
+		{Degraded, Flapping, false},
 	} {
 		actual := test.status.PriorityOver(test.another)
 		if actual != test.expected {
@@ -226,3 +375,14 @@ func (f *fake) Notify(results []Result) error {
 	f.notified++
 	return nil
 }
+
+// slow is a Checker that is not context-aware and
+// takes delay to complete.
+type slow struct {
+	delay time.Duration
+}
+
+func (s slow) Check() (Result, error) {
+	time.Sleep(s.delay)
+	return Result{Timestamp: Timestamp(), Healthy: true}, nil
+}
@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Sparklane/checkup"
+	"github.com/spf13/cobra"
+)
+
+var (
+	cronTimezone    string
+	cronMetricsAddr string
+)
+
+var cronCmd = &cobra.Command{
+	Use:   "cron",
+	Short: "Run checks indefinitely on a cron schedule",
+	Long: `The cron subcommand runs checkups on the cron schedule
+you specify. The result of each check is saved to storage.
+Additionally, if a Notifier is configured, it will be
+called to analyze and potentially notify you of any
+problems.
+
+Checkers that set their own "interval" or "schedule" run
+on their own cadence instead; the schedule given here is
+the default for the other checkers. Checks that are due
+at the same time are stored together.
+
+This command never unblocks, so you must signal the
+program to exit.
+
+The schedule is a standard cron expression with 5 fields
+(minute, hour, day of month, month and day of week), or
+a descriptor such as @hourly, @daily or @every 1h30m. It
+is evaluated in the time zone given with --timezone, or
+else in the local time zone, which is named by the TZ
+environment variable if it is set.
+
+With --metrics-addr, Prometheus metrics of the checks are
+served at /metrics on that address.
+
+Examples:
+
+  $ checkup cron "*/5 * * * *"
+  $ checkup cron "0 7 * * 1-5" --timezone Europe/Paris
+  $ checkup cron @hourly`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fmt.Println(cmd.Long)
+			os.Exit(1)
+		}
+
+		loc, err := loadLocation(cronTimezone)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		schedule, err := checkup.ParseSchedule(args[0], loc)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		c := loadCheckup()
+		if len(c.Checkers) == 0 {
+			log.Fatal("no checkers configured")
+		}
+		if c.Storage == nil {
+			log.Fatal("no storage configured")
+		}
+		serveMetrics(&c, cronMetricsAddr)
+
+		err = c.CheckAndStoreScheduled(context.Background(), schedule)
+		log.Fatal(err)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(cronCmd)
+	cronCmd.Flags().StringVarP(&cronTimezone, "timezone", "z", "", "Time zone in which to evaluate the schedule (default local, or $TZ)")
+	cronCmd.Flags().StringVar(&cronMetricsAddr, "metrics-addr", "", "Address on which to serve Prometheus metrics (default none)")
+}
+
+// loadLocation returns the time zone named name, or the
+// local time zone if name is empty.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(name)
+}
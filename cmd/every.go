@@ -1,15 +1,20 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"net/http"
 	"os"
 	"strings"
 	"time"
 
+	"github.com/Sparklane/checkup"
 	"github.com/spf13/cobra"
 )
 
+var everyMetricsAddr string
+
 var everyCmd = &cobra.Command{
 	Use:   "every",
 	Short: "Run checks indefinitely at an interval",
@@ -19,6 +24,14 @@ Additionally, if a Notifier is configured, it will be
 called to analyze and potentially notify you of any
 problems.
 
+Checkers that set their own "interval" or "schedule" run
+on their own cadence instead; the interval given here is
+the default for the other checkers. Checks that are due
+at the same time are stored together.
+
+With --metrics-addr, Prometheus metrics of the checks are
+served at /metrics on that address.
+
 This command never unblocks, so you must signal the
 program to exit.
 
@@ -31,28 +44,15 @@ Examples:
 
   $ checkup every 10m
   $ checkup every day
-  $ checkup every 1h30m`,
+  $ checkup every 1h30m
+  $ checkup every 5m --metrics-addr :9100`,
 	Run: func(cmd *cobra.Command, args []string) {
 		if len(args) != 1 {
 			fmt.Println(cmd.Long)
 			os.Exit(1)
 		}
 
-		itvlStr := strings.ToLower(args[0])
-		switch itvlStr {
-		case "second":
-			itvlStr = "1s"
-		case "minute":
-			itvlStr = "1m"
-		case "hour":
-			itvlStr = "1h"
-		case "day":
-			itvlStr = "24h"
-		case "week":
-			itvlStr = "168h"
-		}
-
-		interval, err := time.ParseDuration(itvlStr)
+		interval, err := parseInterval(args[0])
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -64,13 +64,48 @@ Examples:
 		if c.Storage == nil {
 			log.Fatal("no storage configured")
 		}
+		serveMetrics(&c, everyMetricsAddr)
 
-		c.CheckAndStoreEvery(interval)
-		select {}
+		err = c.CheckAndStoreScheduled(context.Background(), checkup.Every(interval))
+		log.Fatal(err)
 	},
 }
 
+// parseInterval parses an interval like time.ParseDuration,
+// also accepting second, minute, hour, day and week.
+func parseInterval(s string) (time.Duration, error) {
+	itvlStr := strings.ToLower(s)
+	switch itvlStr {
+	case "second":
+		itvlStr = "1s"
+	case "minute":
+		itvlStr = "1m"
+	case "hour":
+		itvlStr = "1h"
+	case "day":
+		itvlStr = "24h"
+	case "week":
+		itvlStr = "168h"
+	}
+	return time.ParseDuration(itvlStr)
+}
+
+// serveMetrics records metrics of c and serves them at
+// /metrics on addr, in the background. It does nothing if
+// addr is empty.
+func serveMetrics(c *checkup.Checkup, addr string) {
+	if addr == "" {
+		return
+	}
+	c.Metrics = checkup.NewMetrics()
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", c.Metrics.Handler())
+	go func() {
+		log.Fatal(http.ListenAndServe(addr, mux))
+	}()
+}
+
 func init() {
 	RootCmd.AddCommand(everyCmd)
-
+	everyCmd.Flags().StringVar(&everyMetricsAddr, "metrics-addr", "", "Address on which to serve Prometheus metrics (default none)")
 }
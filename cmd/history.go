@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Sparklane/checkup"
+	"github.com/spf13/cobra"
+)
+
+var (
+	historyAbout  string
+	historySince  time.Duration
+	historyFormat string
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history",
+	Short: "Print the timeline of the status of endpoints",
+	Long: `The history subcommand reads the results stored since
+--since ago, 24 hours by default, and prints a timeline
+of each endpoint: the periods during which its status
+stayed the same, with their start time, status, duration
+and first notice or error. A period starts at the first
+check that found its status; the duration of the latest
+period, which is still ongoing, is followed by a "+".
+
+The storage configured in the config file must be
+readable (such as fs, sql or github). Use --about to
+print the timeline of a single endpoint, and --format
+json to print the timelines as JSON.
+
+Examples:
+
+  $ checkup history
+  $ checkup history --about API --since 72h
+  $ checkup history --format json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if historyFormat != "text" && historyFormat != "json" {
+			fmt.Fprintf(os.Stderr, "unknown format %q; want text or json\n", historyFormat)
+			os.Exit(1)
+		}
+
+		c := loadCheckup()
+		reader, ok := c.Storage.(checkup.StorageReader)
+		if !ok {
+			log.Fatal("storage cannot be read from")
+		}
+		timelines, err := checkup.History(reader, historyAbout, time.Now().Add(-historySince))
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if historyFormat == "json" {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(timelines); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		for _, timeline := range timelines {
+			fmt.Println(timeline)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().StringVar(&historyAbout, "about", "", "Title of the endpoint (default all)")
+	historyCmd.Flags().DurationVar(&historySince, "since", 24*time.Hour, "How far back to go")
+	historyCmd.Flags().StringVar(&historyFormat, "format", "text", "Output format: text or json")
+}
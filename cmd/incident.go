@@ -0,0 +1,154 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/Sparklane/checkup"
+	"github.com/spf13/cobra"
+)
+
+var (
+	incidentEndpoints []string
+	incidentStatus    string
+	incidentMessage   string
+	incidentAll       bool
+	incidentFormat    string
+)
+
+var incidentCmd = &cobra.Command{
+	Use:   "incident",
+	Short: "Manage incidents shown on the status page",
+	Long: `The incident subcommands open, update, resolve and list
+incidents: disruptions of service that you want to tell
+your customers or visitors about, with the endpoints they
+affect and a log of updates. Their status is one of
+investigating, identified, monitoring or resolved.
+
+Incidents are kept in the storage of the config file, in
+an incidents.json document next to the check files, which
+the status page shows. The storage must be able to store
+documents (such as fs, sql or github).
+
+Examples:
+
+  $ checkup incident open "Slow API" --endpoints API --message "We are looking into it"
+  $ checkup incident update 1 --status identified --message "A database is overloaded"
+  $ checkup incident resolve 1 --message "The database has been scaled up"
+  $ checkup incident list`,
+}
+
+var incidentOpenCmd = &cobra.Command{
+	Use:   "open <title>",
+	Short: "Open an incident",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 1 {
+			fmt.Println(cmd.UsageString())
+			os.Exit(1)
+		}
+		c := loadCheckup()
+		incident, err := c.OpenIncident(args[0], incidentEndpoints, checkup.IncidentStatus(incidentStatus), incidentMessage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(incident)
+	},
+}
+
+var incidentUpdateCmd = &cobra.Command{
+	Use:   "update <id>",
+	Short: "Post an update of an incident",
+	Run: func(cmd *cobra.Command, args []string) {
+		id := idArg(cmd, args)
+		c := loadCheckup()
+		incident, err := c.UpdateIncident(id, checkup.IncidentStatus(incidentStatus), incidentMessage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(incident)
+	},
+}
+
+var incidentResolveCmd = &cobra.Command{
+	Use:   "resolve <id>",
+	Short: "Resolve an incident",
+	Run: func(cmd *cobra.Command, args []string) {
+		id := idArg(cmd, args)
+		if incidentMessage == "" {
+			incidentMessage = "This incident has been resolved."
+		}
+		c := loadCheckup()
+		incident, err := c.ResolveIncident(id, incidentMessage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Print(incident)
+	},
+}
+
+var incidentListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the incidents, newest first",
+	Run: func(cmd *cobra.Command, args []string) {
+		c := loadCheckup()
+		incidents, err := c.Incidents()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !incidentAll {
+			var active []checkup.Incident
+			for _, incident := range incidents {
+				if incident.Active() {
+					active = append(active, incident)
+				}
+			}
+			incidents = active
+		}
+
+		if incidentFormat == "json" {
+			if incidents == nil {
+				incidents = []checkup.Incident{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(incidents); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		for _, incident := range incidents {
+			fmt.Println(incident)
+		}
+	},
+}
+
+// idArg returns the ID given in args, such as of an
+// incident, or prints the usage of cmd and exits.
+func idArg(cmd *cobra.Command, args []string) int {
+	if len(args) == 1 {
+		if id, err := strconv.Atoi(args[0]); err == nil {
+			return id
+		}
+	}
+	fmt.Println(cmd.UsageString())
+	os.Exit(1)
+	return 0
+}
+
+func init() {
+	RootCmd.AddCommand(incidentCmd)
+	incidentCmd.AddCommand(incidentOpenCmd, incidentUpdateCmd, incidentResolveCmd, incidentListCmd)
+
+	incidentOpenCmd.Flags().StringSliceVarP(&incidentEndpoints, "endpoints", "e", nil, "Titles of the affected endpoints, separated by commas")
+	for _, cmd := range []*cobra.Command{incidentOpenCmd, incidentUpdateCmd} {
+		cmd.Flags().StringVarP(&incidentStatus, "status", "s", "", "Status: investigating, identified, monitoring or resolved")
+	}
+	for _, cmd := range []*cobra.Command{incidentOpenCmd, incidentUpdateCmd, incidentResolveCmd} {
+		cmd.Flags().StringVarP(&incidentMessage, "message", "m", "", "Message to post")
+	}
+	incidentListCmd.Flags().BoolVar(&incidentAll, "all", false, "List resolved incidents as well")
+	incidentListCmd.Flags().StringVar(&incidentFormat, "format", "text", "Output format: text or json")
+}
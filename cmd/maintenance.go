@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Sparklane/checkup"
+	"github.com/spf13/cobra"
+)
+
+var (
+	maintenanceAbout   []string
+	maintenanceStart   string
+	maintenanceEnd     string
+	maintenanceMessage string
+	maintenanceAll     bool
+	maintenanceFormat  string
+)
+
+var maintenanceCmd = &cobra.Command{
+	Use:   "maintenance",
+	Short: "Manage scheduled maintenance",
+	Long: `The maintenance subcommands schedule, list and cancel
+windows of planned work, announced ahead of time. Results
+checked during a window are reported with the maintenance
+status, with its message, and the status page lists the
+upcoming windows.
+
+Scheduled maintenance is kept in the storage of the config
+file, in a maintenance.json document next to the check
+files. The storage must be able to store documents (such
+as fs, sql or github). Recurring windows are configured in
+checkup.json instead.
+
+Times are RFC 3339 times, or dates.
+
+Examples:
+
+  $ checkup maintenance schedule --about API --start 2021-06-05T22:00:00Z --end 2021-06-06T02:00:00Z --message "Database upgrade"
+  $ checkup maintenance list
+  $ checkup maintenance cancel 1`,
+}
+
+var maintenanceScheduleCmd = &cobra.Command{
+	Use:   "schedule",
+	Short: "Schedule maintenance",
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) != 0 || maintenanceStart == "" || maintenanceEnd == "" {
+			fmt.Println(cmd.UsageString())
+			os.Exit(1)
+		}
+		start, err := parseTime(maintenanceStart)
+		if err != nil {
+			log.Fatal(err)
+		}
+		end, err := parseTime(maintenanceEnd)
+		if err != nil {
+			log.Fatal(err)
+		}
+		c := loadCheckup()
+		m, err := c.ScheduleMaintenance(maintenanceAbout, start, end, maintenanceMessage)
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Println(m)
+	},
+}
+
+var maintenanceListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the scheduled maintenance, by start time",
+	Run: func(cmd *cobra.Command, args []string) {
+		c := loadCheckup()
+		scheduled, err := c.MaintenanceSchedule()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !maintenanceAll {
+			now := time.Now()
+			var upcoming []checkup.ScheduledMaintenance
+			for _, m := range scheduled {
+				if !m.Ended(now) {
+					upcoming = append(upcoming, m)
+				}
+			}
+			scheduled = upcoming
+		}
+
+		if maintenanceFormat == "json" {
+			if scheduled == nil {
+				scheduled = []checkup.ScheduledMaintenance{}
+			}
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(scheduled); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+		for _, m := range scheduled {
+			fmt.Println(m)
+		}
+	},
+}
+
+var maintenanceCancelCmd = &cobra.Command{
+	Use:   "cancel <id>",
+	Short: "Cancel scheduled maintenance",
+	Run: func(cmd *cobra.Command, args []string) {
+		id := idArg(cmd, args)
+		c := loadCheckup()
+		if err := c.CancelMaintenance(id); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(maintenanceCmd)
+	maintenanceCmd.AddCommand(maintenanceScheduleCmd, maintenanceListCmd, maintenanceCancelCmd)
+
+	maintenanceScheduleCmd.Flags().StringSliceVarP(&maintenanceAbout, "about", "a", nil, "Titles of the endpoints under maintenance, separated by commas (default all endpoints)")
+	maintenanceScheduleCmd.Flags().StringVar(&maintenanceStart, "start", "", "Start of the window")
+	maintenanceScheduleCmd.Flags().StringVar(&maintenanceEnd, "end", "", "End of the window")
+	maintenanceScheduleCmd.Flags().StringVarP(&maintenanceMessage, "message", "m", "", "Message to show during the window")
+	maintenanceListCmd.Flags().BoolVar(&maintenanceAll, "all", false, "List ended maintenance as well")
+	maintenanceListCmd.Flags().StringVar(&maintenanceFormat, "format", "text", "Output format: text or json")
+}
@@ -0,0 +1,96 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Sparklane/checkup"
+	"github.com/spf13/cobra"
+)
+
+var (
+	mergeQuorum int
+	mergeSince  time.Duration
+	mergeStore  bool
+)
+
+var mergeCmd = &cobra.Command{
+	Use:   "merge [config...]",
+	Short: "Merge the results of several locations",
+	Long: `The merge subcommand reads the latest results stored
+by checkup instances running in different locations, and
+concludes on the status of each endpoint by a quorum of
+the locations. For example, with --quorum 2, an endpoint
+is down only if at least 2 locations found it down.
+
+Each instance should set its own "node" and "location" in
+its configuration. They may share a storage, or use their
+own: the storages read are those of the config files given
+as arguments, or the storage of the config file given with
+--config if there are none.
+
+The merged results are printed to stdout, or stored in the
+storage of the --config file with --store.
+
+Examples:
+
+  $ checkup merge --quorum 2
+  $ checkup merge --since 10m paris.json london.json nyc.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) == 0 {
+			args = []string{configFile}
+		}
+		var readers []checkup.StorageReader
+		for _, file := range args {
+			c, err := readCheckup(file)
+			if err != nil {
+				log.Fatal(err)
+			}
+			reader, ok := c.Storage.(checkup.StorageReader)
+			if !ok {
+				log.Fatalf("%s: storage cannot be read from", file)
+			}
+			readers = append(readers, reader)
+		}
+
+		var since time.Time
+		if mergeSince > 0 {
+			since = time.Now().Add(-mergeSince)
+		}
+		results, err := checkup.MergeLatest(readers, mergeQuorum, since)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		if mergeStore {
+			c := loadCheckup()
+			if c.Storage == nil {
+				log.Fatal("no storage configured")
+			}
+			if err := c.Storage.Store(results); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+
+		allHealthy := true
+		for _, result := range results {
+			fmt.Println(result)
+			if !result.Healthy && !result.Maintenance {
+				allHealthy = false
+			}
+		}
+		if !allHealthy {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(mergeCmd)
+	mergeCmd.Flags().IntVarP(&mergeQuorum, "quorum", "q", 0, "Number of locations that must agree on a failure (default majority)")
+	mergeCmd.Flags().DurationVar(&mergeSince, "since", 0, "Ignore results older than this")
+	mergeCmd.Flags().BoolVar(&mergeStore, "store", false, "Store merged results")
+}
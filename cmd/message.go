@@ -12,8 +12,9 @@ import (
 var about string
 
 var messageCmd = &cobra.Command{
-	Use:   "message",
-	Short: "Post a status message/update",
+	Use:        "message",
+	Short:      "Post a status message/update",
+	Deprecated: "use \"checkup incident\" instead; messages disappear from the status page as results expire.",
 	Long: `The message subcommand allows you to post updates to
 to your status page for a certain endpoint. This is
 helpful (and responsible of you) when your service is
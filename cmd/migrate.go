@@ -0,0 +1,247 @@
+package cmd
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Sparklane/checkup"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateFrom   string
+	migrateTo     string
+	archiveFormat string
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Copy the check files of a storage into another",
+	Long: `The migrate subcommand copies every check file of a
+storage into another, keeping their names and timestamps,
+such as to move from fs to sql storage without losing
+history. Check files that are already in the destination
+are skipped, so an interrupted migration can be run
+again.
+
+--from and --to are JSON files with the configuration of
+the storages, as in the "storage" of checkup.json, or
+config files whose storage is used. The source must be
+readable (such as fs, sql or github).
+
+Examples:
+
+  $ checkup migrate --from fs.json --to sql.json
+  $ checkup migrate --from checkup.json --to github.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if migrateFrom == "" || migrateTo == "" {
+			fmt.Println(cmd.Long)
+			os.Exit(1)
+		}
+		from, err := readStorage(migrateFrom)
+		if err != nil {
+			log.Fatal(err)
+		}
+		to, err := readStorage(migrateTo)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reader, ok := from.(checkup.StorageReader)
+		if !ok {
+			log.Fatalf("%s: storage cannot be read from", migrateFrom)
+		}
+		writer, ok := to.(checkup.StorageWriter)
+		if !ok {
+			log.Fatalf("%s: storage cannot store check files", migrateTo)
+		}
+
+		n, err := checkup.Migrate(reader, writer)
+		fmt.Printf("%d check files copied\n", n)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var exportCmd = &cobra.Command{
+	Use:   "export [file]",
+	Short: "Export the check files of the storage",
+	Long: `The export subcommand writes every check file of the
+storage of the config file, oldest first, to the given
+file, or to stdout. The storage must be readable (such
+as fs, sql or github).
+
+Check files are exported as a JSON object per line
+(ndjson), with their name, timestamp and results, or as
+a tar archive (tar) of the check files and their
+index.json, like the fs storage. The format is guessed
+from the extension of the file (.ndjson, .tar, .tar.gz or
+.tgz), unless given with --format. Archives whose name
+ends with .gz or .tgz are compressed with gzip.
+
+Examples:
+
+  $ checkup export history.tar.gz
+  $ checkup export > history.ndjson`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			fmt.Println(cmd.Long)
+			os.Exit(1)
+		}
+		c := loadCheckup()
+		reader, ok := c.Storage.(checkup.StorageReader)
+		if !ok {
+			log.Fatal("storage cannot be read from")
+		}
+
+		var w io.WriteCloser = os.Stdout
+		var name string
+		if len(args) == 1 {
+			name = args[0]
+			f, err := os.Create(name)
+			if err != nil {
+				log.Fatal(err)
+			}
+			w = f
+		}
+		if compressed(name) {
+			w = &gzipFile{gzip.NewWriter(w), w}
+		}
+		if err := checkup.Export(w, reader, formatOf(name)); err != nil {
+			log.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import [file]",
+	Short: "Import check files into the storage",
+	Long: `The import subcommand stores the check files exported
+with the export subcommand, read from the given file or
+from stdin, into the storage of the config file, keeping
+their names and timestamps. Check files that are already
+in the storage are skipped.
+
+The format is guessed from the extension of the file, as
+with export, unless given with --format.
+
+Examples:
+
+  $ checkup import history.tar.gz
+  $ checkup import < history.ndjson`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if len(args) > 1 {
+			fmt.Println(cmd.Long)
+			os.Exit(1)
+		}
+		c := loadCheckup()
+		writer, ok := c.Storage.(checkup.StorageWriter)
+		if !ok {
+			log.Fatal("storage cannot store check files")
+		}
+
+		var r io.Reader = os.Stdin
+		var name string
+		if len(args) == 1 {
+			name = args[0]
+			f, err := os.Open(name)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer f.Close()
+			r = f
+		}
+		if compressed(name) {
+			zr, err := gzip.NewReader(r)
+			if err != nil {
+				log.Fatal(err)
+			}
+			r = zr
+		}
+		n, err := checkup.Import(r, writer, formatOf(name))
+		fmt.Printf("%d check files imported\n", n)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+// readStorage reads the storage configured in file, which
+// holds either the configuration of a storage or a whole
+// config.
+func readStorage(file string) (checkup.Storage, error) {
+	b, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var probe struct {
+		Provider string `json:"provider"`
+	}
+	if err := json.Unmarshal(b, &probe); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if probe.Provider != "" {
+		b = append(append([]byte(`{"storage":`), b...), '}')
+	}
+	var c checkup.Checkup
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, fmt.Errorf("%s: %v", file, err)
+	}
+	if c.Storage == nil {
+		return nil, fmt.Errorf("%s: no storage configured", file)
+	}
+	return c.Storage, nil
+}
+
+// formatOf returns the archive format given with --format,
+// or else the format of the file name.
+func formatOf(name string) string {
+	switch {
+	case archiveFormat != "":
+		return archiveFormat
+	case strings.HasSuffix(name, ".tar"), strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tar"
+	}
+	return "ndjson"
+}
+
+// compressed returns whether the file name is compressed
+// with gzip.
+func compressed(name string) bool {
+	return strings.HasSuffix(name, ".gz") || strings.HasSuffix(name, ".tgz")
+}
+
+// gzipFile is a gzip.Writer that closes the file it
+// writes to when closed.
+type gzipFile struct {
+	*gzip.Writer
+	file io.Closer
+}
+
+func (g *gzipFile) Close() error {
+	if err := g.Writer.Close(); err != nil {
+		g.file.Close()
+		return err
+	}
+	return g.file.Close()
+}
+
+func init() {
+	RootCmd.AddCommand(migrateCmd)
+	RootCmd.AddCommand(exportCmd)
+	RootCmd.AddCommand(importCmd)
+	migrateCmd.Flags().StringVar(&migrateFrom, "from", "", "JSON file of the storage to copy from")
+	migrateCmd.Flags().StringVar(&migrateTo, "to", "", "JSON file of the storage to copy to")
+	for _, cmd := range []*cobra.Command{exportCmd, importCmd} {
+		cmd.Flags().StringVar(&archiveFormat, "format", "", "Format: ndjson or tar (default from the file extension, or ndjson)")
+	}
+}
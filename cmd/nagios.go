@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Sparklane/checkup"
+	"github.com/spf13/cobra"
+)
+
+var nagiosOnly string
+
+var nagiosCmd = &cobra.Command{
+	Use:   "nagios",
+	Short: "Run a checker as a Nagios or Icinga plugin",
+	Long: `The nagios subcommand runs a single checker of the config
+file, chosen by its endpoint name with --only, and prints
+its result as a Nagios plugin would: a status line with
+performance data (round trip times in seconds, the
+threshold and the number of days left on the
+certificate). It exits with 0, 1, 2 or 3 if the endpoint
+is healthy, degraded, down or unknown. Endpoints in
+maintenance are reported as healthy.
+
+The --only flag may be omitted if there is a single
+checker. Results are neither stored nor notified.
+
+Examples:
+
+  $ checkup nagios --only "Example HTTP"
+  $ checkup nagios -c /etc/checkup/web.json`,
+	Run: func(cmd *cobra.Command, args []string) {
+		c, err := readCheckup(configFile)
+		if err != nil {
+			fmt.Printf("CHECKUP UNKNOWN - %v\n", err)
+			os.Exit(checkup.NagiosUnknown)
+		}
+		line, code := c.CheckNagios(nagiosOnly)
+		fmt.Println(line)
+		os.Exit(code)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(nagiosCmd)
+	nagiosCmd.Flags().StringVar(&nagiosOnly, "only", "", "Endpoint name of the checker to run")
+}
@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/Sparklane/checkup"
+	"github.com/Sparklane/checkup/report"
+	"github.com/spf13/cobra"
+)
+
+var (
+	reportFrom   string
+	reportTo     string
+	reportFormat string
+)
+
+var reportCmd = &cobra.Command{
+	Use:   "report",
+	Short: "Report the availability of endpoints over a period",
+	Long: `The report subcommand reads the results stored over a
+period and reports, for each endpoint, its availability,
+downtime, number of incidents, mean time to recovery and
+round trip time percentiles. Time spent in maintenance
+is left out. The storage configured in the config file
+must be readable (such as fs, sql or github).
+
+The period goes from --from to --to, which are dates
+such as 2026-09-01 (at midnight UTC) or times in RFC 3339
+format. By default, it covers the last 30 days.
+
+The report is printed as markdown, csv or html, as chosen
+with --format.
+
+Examples:
+
+  $ checkup report --from 2026-09-01 --to 2026-10-01
+  $ checkup report --from 2026-09-01 --format html > september.html`,
+	Run: func(cmd *cobra.Command, args []string) {
+		to := time.Now()
+		if reportTo != "" {
+			var err error
+			to, err = parseTime(reportTo)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		from := to.AddDate(0, 0, -30)
+		if reportFrom != "" {
+			var err error
+			from, err = parseTime(reportFrom)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+
+		c := loadCheckup()
+		reader, ok := c.Storage.(checkup.StorageReader)
+		if !ok {
+			log.Fatal("storage cannot be read from")
+		}
+		r, err := report.Compute(reader, from, to)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := r.Write(os.Stdout, reportFormat); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	},
+}
+
+// parseTime parses a date such as 2026-09-01, at midnight
+// UTC, or a time in RFC 3339 format.
+func parseTime(s string) (time.Time, error) {
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return t, fmt.Errorf("invalid time %q: want a date such as 2006-01-02 or an RFC 3339 time", s)
+	}
+	return t, nil
+}
+
+func init() {
+	RootCmd.AddCommand(reportCmd)
+	reportCmd.Flags().StringVar(&reportFrom, "from", "", "Start of the period (default 30 days before --to)")
+	reportCmd.Flags().StringVar(&reportTo, "to", "", "End of the period (default now)")
+	reportCmd.Flags().StringVar(&reportFormat, "format", "markdown", "Output format: markdown, csv or html")
+}
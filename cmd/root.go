@@ -6,6 +6,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"strings"
 
 	"github.com/Sparklane/checkup"
 	"github.com/spf13/cobra"
@@ -14,6 +15,7 @@ import (
 var configFile string
 var storeResults bool
 var printLogs bool
+var outputFormat string
 
 // RootCmd represents the base command when called without any subcommands
 var RootCmd = &cobra.Command{
@@ -28,8 +30,14 @@ You can specify a different file location using the
 --config/-c flag.
 
 Running checkup without any arguments will invoke
-a single checkup and print results to stdout. To
-store the results of the check, use --store.`,
+a single checkup and print results to stdout, and
+exits with status 1 if any endpoint is not healthy.
+To store the results of the check, use --store. The
+results are notified once printed or stored.
+
+Results are printed as text by default. Use --format
+to print them as json, junit (JUnit XML) or tap (Test
+Anything Protocol), such as for test reporters in CI.`,
 
 	Run: func(cmd *cobra.Command, args []string) {
 		if printLogs {
@@ -38,6 +46,10 @@ store the results of the check, use --store.`,
 
 		allHealthy := true
 		c := loadCheckup()
+		if !validFormat(outputFormat) {
+			fmt.Fprintf(os.Stderr, "unknown format %q; want one of %s\n", outputFormat, strings.Join(checkup.Formats, ", "))
+			os.Exit(1)
+		}
 
 		if storeResults {
 			if c.Storage == nil {
@@ -45,22 +57,28 @@ store the results of the check, use --store.`,
 			}
 		}
 
-		results, err := c.Check()
-		if err != nil {
-			log.Fatal(err)
-		}
-
 		if storeResults {
-			err := c.Storage.Store(results)
-			if err != nil {
+			// the results are stored even if notifiers fail
+			if err := c.CheckAndStore(); err != nil {
 				log.Fatal(err)
 			}
 			return
 		}
 
+		// the results are printed before they are notified,
+		// so that a notifier that fails doesn't hold them back
+		results, err := c.WithoutNotifiers().Check()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := checkup.WriteResults(os.Stdout, outputFormat, results); err != nil {
+			log.Fatal(err)
+		}
+		if err := c.Notify(results); err != nil {
+			log.Println(err)
+		}
 		for _, result := range results {
-			fmt.Println(result)
-			if !result.Healthy {
+			if !result.Healthy && !result.Maintenance {
 				allHealthy = false
 			}
 		}
@@ -72,18 +90,35 @@ store the results of the check, use --store.`,
 }
 
 func loadCheckup() checkup.Checkup {
-	configBytes, err := ioutil.ReadFile(configFile)
+	c, err := readCheckup(configFile)
 	if err != nil {
 		log.Fatal(err)
 	}
+	return c
+}
 
+// readCheckup reads the Checkup configured in file.
+func readCheckup(file string) (checkup.Checkup, error) {
 	var c checkup.Checkup
-	err = json.Unmarshal(configBytes, &c)
+	configBytes, err := ioutil.ReadFile(file)
 	if err != nil {
-		log.Fatal(err)
+		return c, err
 	}
+	if err := json.Unmarshal(configBytes, &c); err != nil {
+		return c, fmt.Errorf("%s: %v", file, err)
+	}
+	return c, nil
+}
 
-	return c
+// validFormat returns whether format is one of
+// checkup.Formats.
+func validFormat(format string) bool {
+	for _, f := range checkup.Formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
 }
 
 // Execute adds all child commands to the root command sets flags appropriately.
@@ -99,4 +134,5 @@ func init() {
 	RootCmd.PersistentFlags().StringVarP(&configFile, "config", "c", "checkup.json", "JSON config file")
 	RootCmd.Flags().BoolVar(&storeResults, "store", false, "Store results")
 	RootCmd.Flags().BoolVar(&printLogs, "v", false, "Enable logging to standard output")
+	RootCmd.Flags().StringVar(&outputFormat, "format", "text", "Output format: text, json, junit or tap")
 }
@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/Sparklane/checkup"
+	"github.com/Sparklane/checkup/statuspage"
+	"github.com/spf13/cobra"
+)
+
+var (
+	serveAddr       string
+	serveStatusPage string
+	serveEvery      string
+	serveCron       string
+	serveTimezone   string
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the status page, check files and a JSON API",
+	Long: `The serve subcommand runs an HTTP server for the status
+page, so that it doesn't need to be hosted separately.
+It serves:
+
+  /                               the status page
+  /check_files/index.json         the index of the storage
+  /check_files/<name>             the check files
+  /api/status                     the latest status of each endpoint
+  /api/endpoints/<title>/history  the results of an endpoint (?since=24h)
+  /metrics                        Prometheus metrics
+
+Check files are read from the storage configured in the
+config file, which must be readable (such as fs, sql or
+github). The status page is built into checkup; use
+--statuspage to serve your own copy instead. Its
+js/config.js should have its storage url set to
+"/check_files".
+
+With --every or --cron, checks are also run and stored
+in the same process, as with the every and cron
+subcommands.
+
+Examples:
+
+  $ checkup serve
+  $ checkup serve --addr :8080 --every 5m
+  $ checkup serve --statuspage ./statuspage --cron "*/5 * * * *"`,
+	Run: func(cmd *cobra.Command, args []string) {
+		c := loadCheckup()
+		reader, ok := c.Storage.(checkup.StorageReader)
+		if !ok {
+			log.Fatal("storage cannot be read from")
+		}
+
+		var assets http.FileSystem
+		if serveStatusPage != "" {
+			assets = http.Dir(serveStatusPage)
+		} else {
+			assets = http.FS(statuspage.Assets)
+		}
+
+		metrics := checkup.NewMetrics()
+		c.Metrics = metrics
+
+		schedule, err := serveSchedule()
+		if err != nil {
+			log.Fatal(err)
+		}
+		if schedule != nil {
+			if len(c.Checkers) == 0 {
+				log.Fatal("no checkers configured")
+			}
+			go func() {
+				err := c.CheckAndStoreScheduled(context.Background(), schedule)
+				log.Fatal(err)
+			}()
+		}
+
+		server := &checkup.Server{Storage: reader, StatusPage: assets, Metrics: metrics}
+		log.Fatal(http.ListenAndServe(serveAddr, server))
+	},
+}
+
+// serveSchedule returns the schedule given with --every or
+// --cron, or nil if there is none.
+func serveSchedule() (checkup.Schedule, error) {
+	switch {
+	case serveEvery != "" && serveCron != "":
+		return nil, fmt.Errorf("--every and --cron are mutually exclusive")
+	case serveEvery != "":
+		interval, err := parseInterval(serveEvery)
+		if err != nil {
+			return nil, err
+		}
+		return checkup.Every(interval), nil
+	case serveCron != "":
+		loc, err := loadLocation(serveTimezone)
+		if err != nil {
+			return nil, err
+		}
+		return checkup.ParseSchedule(serveCron, loc)
+	}
+	return nil, nil
+}
+
+func init() {
+	RootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "Address to listen on")
+	serveCmd.Flags().StringVar(&serveStatusPage, "statuspage", "", "Directory of the status page to serve (default built-in)")
+	serveCmd.Flags().StringVar(&serveEvery, "every", "", "Also run checks at this interval")
+	serveCmd.Flags().StringVar(&serveCron, "cron", "", "Also run checks on this cron schedule")
+	serveCmd.Flags().StringVarP(&serveTimezone, "timezone", "z", "", "Time zone in which to evaluate the cron schedule (default local, or $TZ)")
+}
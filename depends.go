@@ -0,0 +1,187 @@
+package checkup
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Titler is implemented by checkers that can tell the
+// endpoint name (title) of their results without checking.
+// All the checkers of this package implement it.
+type Titler interface {
+	Title() string
+}
+
+// nameOf returns the endpoint name of checker, which is
+// the "endpoint_name" in its configuration, or "" if it
+// doesn't have one. Checkers that aren't Titlers are
+// marshaled to find it.
+func nameOf(checker Checker) string {
+	if t, ok := checker.(Titler); ok {
+		return t.Title()
+	}
+	b, err := json.Marshal(checker)
+	if err != nil {
+		return ""
+	}
+	var named struct {
+		Name string `json:"endpoint_name"`
+	}
+	if err := json.Unmarshal(b, &named); err != nil {
+		return ""
+	}
+	return named.Name
+}
+
+// dependencyGraph returns, for each of checkers, the
+// indices of the checkers it depends on. Endpoint names
+// are matched case-insensitively; the names that match
+// none of checkers are returned in unknown.
+func dependencyGraph(checkers []Checker) (deps [][]int, unknown []string) {
+	byName := make(map[string][]int)
+	for i, checker := range checkers {
+		name := strings.ToLower(nameOf(checker))
+		byName[name] = append(byName[name], i)
+	}
+	deps = make([][]int, len(checkers))
+	for i, checker := range checkers {
+		for _, name := range optionsOf(checker).DependsOn {
+			indices, ok := byName[strings.ToLower(name)]
+			if !ok || name == "" {
+				unknown = append(unknown, name)
+				continue
+			}
+			deps[i] = append(deps[i], indices...)
+		}
+	}
+	return deps, unknown
+}
+
+// dependencyOrder returns the indices of checkers in an
+// order where each checker comes after the checkers it
+// depends on. It returns an error if the dependencies
+// form a cycle.
+func dependencyOrder(checkers []Checker, deps [][]int) ([]int, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	order := make([]int, 0, len(checkers))
+	marks := make([]int, len(checkers))
+	var path []int
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		switch marks[i] {
+		case visited:
+			return nil
+		case visiting:
+			var names []string
+			for j := len(path) - 1; j >= 0; j-- {
+				names = append([]string{nameOf(checkers[path[j]])}, names...)
+				if path[j] == i {
+					break
+				}
+			}
+			names = append(names, nameOf(checkers[i]))
+			return fmt.Errorf("dependency cycle: %s", strings.Join(names, " -> "))
+		}
+		marks[i] = visiting
+		path = append(path, i)
+		for _, j := range deps[i] {
+			if err := visit(j); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		marks[i] = visited
+		order = append(order, i)
+		return nil
+	}
+
+	for i := range checkers {
+		if err := visit(i); err != nil {
+			return nil, err
+		}
+	}
+	return order, nil
+}
+
+// dependencyLayers groups the indices in order, which is
+// a dependency order, into layers: each checker is in the
+// layer after the last of the checkers it depends on, so
+// that running the layers in turn runs the dependencies of
+// each checker before it.
+func dependencyLayers(order []int, deps [][]int) [][]int {
+	var layers [][]int
+	level := make([]int, len(deps))
+	for _, i := range order {
+		for _, j := range deps[i] {
+			if level[j]+1 > level[i] {
+				level[i] = level[j] + 1
+			}
+		}
+		if level[i] == len(layers) {
+			layers = append(layers, nil)
+		}
+		layers[level[i]] = append(layers[level[i]], i)
+	}
+	return layers
+}
+
+// checkDependencies returns an error if checkers depend on
+// endpoints that none of them checks, or if their
+// dependencies form a cycle.
+func checkDependencies(checkers []Checker) error {
+	deps, unknown := dependencyGraph(checkers)
+	if len(unknown) > 0 {
+		return fmt.Errorf("depends_on: unknown endpoint %q", unknown[0])
+	}
+	_, err := dependencyOrder(checkers, deps)
+	return err
+}
+
+// markUnreachable evaluates results, which are those of
+// checkers, in dependency order, and marks the ones that
+// failed while an endpoint they depend on was down as
+// unreachable due to that endpoint. Dependencies that
+// aren't among checkers are looked up by lowercase title
+// in others, which may be nil.
+func markUnreachable(checkers []Checker, results []Result, others map[string]Result) error {
+	deps, _ := dependencyGraph(checkers)
+	order, err := dependencyOrder(checkers, deps)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range order {
+		if results[i].Healthy || results[i].UnreachableDueTo != "" {
+			continue
+		}
+		for _, j := range deps[i] {
+			if results[j].Down {
+				markDependent(&results[i], results[j].Title)
+				break
+			}
+		}
+		if results[i].UnreachableDueTo != "" || others == nil {
+			continue
+		}
+		for _, name := range optionsOf(checkers[i]).DependsOn {
+			if other, ok := others[strings.ToLower(name)]; ok && other.Down {
+				markDependent(&results[i], other.Title)
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// markDependent marks result as unreachable due to the
+// endpoint titled dependency.
+func markDependent(result *Result, dependency string) {
+	result.UnreachableDueTo = dependency
+	result.Notice = fmt.Sprintf("unreachable due to %s", dependency)
+}
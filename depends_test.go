@@ -0,0 +1,124 @@
+package checkup
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDependencyOrder(t *testing.T) {
+	checkers := []Checker{
+		named{Name: "app", CheckerOptions: CheckerOptions{DependsOn: []string{"API", "lb"}}},
+		named{Name: "api", CheckerOptions: CheckerOptions{DependsOn: []string{"lb"}}},
+		named{Name: "lb"},
+	}
+	deps, unknown := dependencyGraph(checkers)
+	if len(unknown) != 0 {
+		t.Errorf("Expected no unknown dependencies, got %v", unknown)
+	}
+	order, err := dependencyOrder(checkers, deps)
+	if err != nil {
+		t.Fatalf("Didn't expect an error: %v", err)
+	}
+	if got, want := order, []int{2, 1, 0}; len(got) != len(want) || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("Expected order %v, got %v", want, got)
+	}
+	if got, want := fmt.Sprint(dependencyLayers(order, deps)), "[[2] [1] [0]]"; got != want {
+		t.Errorf("Expected layers %s, got %s", want, got)
+	}
+
+	checkers[2] = named{Name: "lb", CheckerOptions: CheckerOptions{DependsOn: []string{"app"}}}
+	deps, _ = dependencyGraph(checkers)
+	_, err = dependencyOrder(checkers, deps)
+	if err == nil || !strings.Contains(err.Error(), "app -> api -> lb -> app") {
+		t.Errorf("Expected an error describing the cycle, got: %v", err)
+	}
+
+	if err := checkDependencies([]Checker{named{Name: "a", CheckerOptions: CheckerOptions{DependsOn: []string{"b"}}}}); err == nil {
+		t.Error("Expected an error for an unknown dependency, didn't get one")
+	}
+}
+
+func TestCheckUnreachable(t *testing.T) {
+	c := Checkup{Checkers: []Checker{
+		named{Name: "app", Down: true, CheckerOptions: CheckerOptions{DependsOn: []string{"api"}}},
+		named{Name: "api", Down: true, CheckerOptions: CheckerOptions{DependsOn: []string{"lb"}}},
+		named{Name: "lb", Down: true},
+		named{Name: "db", Down: true, CheckerOptions: CheckerOptions{DependsOn: []string{"cache"}}},
+		named{Name: "cache"},
+	}}
+	results, err := c.Check()
+	if err != nil {
+		t.Fatalf("Didn't expect an error: %v", err)
+	}
+	for i, want := range []string{"api", "lb", "", "", ""} {
+		if got := results[i].UnreachableDueTo; got != want {
+			t.Errorf("Result %d: Expected to be unreachable due to %q, got %q", i, want, got)
+		}
+	}
+	if got, want := results[1].Notice, "unreachable due to lb"; got != want {
+		t.Errorf("Expected notice %q, got %q", want, got)
+	}
+	if !results[0].Down {
+		t.Error("Expected an unreachable result to stay down")
+	}
+
+	// dependencies run separately are looked up by title
+	results = []Result{{Title: "app", Down: true}}
+	latest := map[string]Result{"api": {Title: "api", Down: true}}
+	if err := markUnreachable(c.Checkers[:1], results, latest); err != nil {
+		t.Fatalf("Didn't expect an error: %v", err)
+	}
+	if got, want := results[0].UnreachableDueTo, "api"; got != want {
+		t.Errorf("Expected to be unreachable due to %q, got %q", want, got)
+	}
+}
+
+func TestCheckDependenciesFirst(t *testing.T) {
+	var mu sync.Mutex
+	var done []string
+	check := func(name string, delay time.Duration, deps ...string) Checker {
+		return ordered{named{Name: name, CheckerOptions: CheckerOptions{DependsOn: deps}}, delay, func() {
+			mu.Lock()
+			done = append(done, name)
+			mu.Unlock()
+		}}
+	}
+	c := Checkup{Checkers: []Checker{
+		check("app", 0, "api"),
+		check("api", 0, "lb"),
+		check("lb", 20*time.Millisecond),
+		check("db", 0),
+	}}
+	if _, err := c.Check(); err != nil {
+		t.Fatalf("Didn't expect an error: %v", err)
+	}
+	if got, want := strings.Join(done, " "), "db lb api app"; got != want {
+		t.Errorf("Expected checks to complete in order %q, got %q", want, got)
+	}
+}
+
+// ordered is a named checker that takes delay to check,
+// and calls done once checked.
+type ordered struct {
+	named
+	delay time.Duration
+	done  func()
+}
+
+func (o ordered) Check() (Result, error) {
+	time.Sleep(o.delay)
+	o.done()
+	return o.named.Check()
+}
+
+func TestJSONDependencyCycle(t *testing.T) {
+	jsonBytes := []byte(`{"checkers":[{"type":"tcp","endpoint_name":"a","endpoint_url":"example.com:80","depends_on":["b"]},{"type":"tcp","endpoint_name":"b","endpoint_url":"example.com:443","depends_on":["a"]}]}`)
+	var c Checkup
+	if err := json.Unmarshal(jsonBytes, &c); err == nil {
+		t.Error("Expected an error for a dependency cycle, didn't get one")
+	}
+}
@@ -1,13 +1,17 @@
 package checkup
 
 import (
-	"fmt"
+	"context"
 	"net"
 	"time"
 
 	"github.com/miekg/dns"
 )
 
+func init() {
+	RegisterChecker("dns", func() Checker { return DNSChecker{} })
+}
+
 // DNSChecker implements a Checker for TCP endpoints.
 type DNSChecker struct {
 	// Name is the name of the endpoint.
@@ -29,24 +33,42 @@ type DNSChecker struct {
 	// Attempts is how many requests the client will
 	// make to the endpoint in a single check.
 	Attempts int `json:"attempts,omitempty"`
+	// CheckerOptions are the settings common to all checkers.
+	CheckerOptions
+}
+
+// Title returns the endpoint name of c.
+func (c DNSChecker) Title() string {
+	return c.Name
 }
 
 // Check performs checks using c according to its configuration.
 // An error is only returned if there is a configuration error.
 func (c DNSChecker) Check() (Result, error) {
+	return c.CheckContext(context.Background())
+}
+
+// CheckContext performs checks like Check, aborting pending
+// queries and attempts once ctx is done.
+func (c DNSChecker) CheckContext(ctx context.Context) (Result, error) {
 	if c.Attempts < 1 {
 		c.Attempts = 1
 	}
+	if err := c.validate(); err != nil {
+		return Result{}, err
+	}
 
 	result := Result{Title: c.Name, Endpoint: c.URL, Timestamp: Timestamp()}
-	result.Times = c.doChecks()
+	result.Times, result.Details = c.doChecks(ctx)
 
 	return c.conclude(result), nil
 }
 
-// doChecks executes and returns each attempt.
-func (c DNSChecker) doChecks() Attempts {
+// doChecks executes and returns each attempt, along with
+// the details of the last answer received.
+func (c DNSChecker) doChecks(ctx context.Context) (Attempts, map[string]interface{}) {
 	var conn net.Conn
+	var details map[string]interface{}
 
 	timeout := c.Timeout
 	if timeout == 0 {
@@ -56,6 +78,10 @@ func (c DNSChecker) doChecks() Attempts {
 	checks := make(Attempts, c.Attempts)
 	for i := 0; i < c.Attempts; i++ {
 		var err error
+		if err = ctx.Err(); err != nil {
+			checks[i].Error = attemptError(ctx, err)
+			continue
+		}
 		start := time.Now()
 
 		if c.Host != "" {
@@ -66,20 +92,31 @@ func (c DNSChecker) doChecks() Attempts {
 			m1.Question = make([]dns.Question, 1)
 			m1.Question[0] = dns.Question{Name: hostname, Qtype: dns.TypeA, Qclass: dns.ClassINET}
 			d := new(dns.Client)
-			_, _, err := d.Exchange(m1, c.URL)
+			answer, _, err := d.ExchangeContext(ctx, m1, c.URL)
 			if err != nil {
-				checks[i].Error = err.Error()
+				checks[i].Error = attemptError(ctx, err)
 				continue
 			}
+			details = map[string]interface{}{"rcode": dns.RcodeToString[answer.Rcode]}
+			var addrs []string
+			for _, rr := range answer.Answer {
+				if a, ok := rr.(*dns.A); ok {
+					addrs = append(addrs, a.A.String())
+				}
+			}
+			if len(addrs) > 0 {
+				details["answers"] = addrs
+			}
 		}
-		if conn, err = net.DialTimeout("tcp", c.URL, c.Timeout); err != nil {
-			checks[i].Error = err.Error()
+		dialer := &net.Dialer{Timeout: c.Timeout}
+		if conn, err = dialer.DialContext(ctx, "tcp", c.URL); err != nil {
+			checks[i].Error = attemptError(ctx, err)
 		} else {
 			conn.Close()
 		}
 		checks[i].RTT = time.Since(start)
 	}
-	return checks
+	return checks, details
 }
 
 // conclude takes the data in result from the attempts and
@@ -89,22 +126,19 @@ func (c DNSChecker) doChecks() Attempts {
 func (c DNSChecker) conclude(result Result) Result {
 	result.ThresholdRTT = c.ThresholdRTT
 
+	stats := result.ComputeStats()
+
 	// Check errors (down)
-	for i := range result.Times {
-		if result.Times[i].Error != "" {
-			result.Down = true
-			return result
-		}
+	if c.down(stats) {
+		result.Down = true
+		return result
 	}
 
 	// Check round trip time (degraded)
-	if c.ThresholdRTT > 0 {
-		stats := result.ComputeStats()
-		if stats.Median > c.ThresholdRTT {
-			result.Notice = fmt.Sprintf("median round trip time exceeded threshold (%s)", c.ThresholdRTT)
-			result.Degraded = true
-			return result
-		}
+	if degraded, notice := c.degraded(stats, c.ThresholdRTT); degraded {
+		result.Notice = notice
+		result.Degraded = true
+		return result
 	}
 
 	result.Healthy = true
@@ -0,0 +1,416 @@
+package checkup
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html/template"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drone/envsubst"
+)
+
+func init() {
+	RegisterNotifier("email", func() Notifier { return Email{} })
+}
+
+// DefaultSubjectTemplate is the default template of the
+// subject of the emails of an Email notifier.
+const DefaultSubjectTemplate = `{{if eq (len .Messages) 1}}{{(index .Messages 0).Title}}` +
+	`{{else}}{{len .Messages}} endpoints changed status{{end}}`
+
+// DefaultEmailTimeout is how long an Email notifier waits
+// for the SMTP server, if not configured.
+const DefaultEmailTimeout = 30 * time.Second
+
+// Email is a notifier that sends emails over SMTP about the
+// transitions of endpoints. The transitions found by a call
+// to Notify are sent in a single email, as plain text and
+// HTML.
+type Email struct {
+	// Host and Port are the address of the SMTP server.
+	// Port defaults to 587 with STARTTLS, 465 with TLS
+	// and 25 otherwise.
+	Host string `json:"host"`
+	Port int    `json:"port,omitempty"`
+
+	// TLS is how the connection to the server is secured:
+	// "starttls" (the default), which upgrades the
+	// connection after connecting, "tls", which connects
+	// with TLS right away, or "none".
+	TLS string `json:"tls,omitempty"`
+
+	// Username and Password, if set, authenticate with the
+	// server, with the Auth mechanism: "plain" (default) or
+	// "login". Environment variables in them, such as
+	// ${SMTP_PASSWORD}, are expanded.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	Auth     string `json:"auth,omitempty"`
+
+	// From is the sender of emails, and To are their
+	// recipients.
+	From string   `json:"from"`
+	To   []string `json:"to"`
+
+	// SubjectTemplate is the text/template template of the
+	// subject of emails, executed with the .Messages of the
+	// email, each of which is a Message with its rendered
+	// .Title and .Body. Default is DefaultSubjectTemplate.
+	SubjectTemplate string `json:"subject_template,omitempty"`
+
+	// Timeout is how long to wait for the server. Default
+	// is DefaultEmailTimeout.
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// tlsConfig is the config to use when securing the
+	// connection to the server. If not set, the certificate
+	// of the server is verified for Host.
+	tlsConfig *tls.Config
+
+	NotifierOptions
+}
+
+// emailData is what the templates of emails are executed
+// with.
+type emailData struct {
+	Messages []renderedMessage
+}
+
+// emailHTML is the template of the HTML part of emails,
+// in which each message is marked with the color of its
+// transition.
+var emailHTML = template.Must(template.New("email").Funcs(template.FuncMap{
+	"color": emailColor,
+}).Parse(`<!DOCTYPE html>
+<html><body style="font-family: sans-serif;">
+{{range .Messages}}<div style="border-left: 4px solid {{color .}}; padding: 4px 12px; margin-bottom: 12px;">
+<h3 style="margin: 0 0 6px 0;">{{.Title}}</h3>
+<p style="margin: 0; white-space: pre-line;">{{.Body}}</p>
+</div>
+{{end}}</body></html>
+`))
+
+// emailColor returns the color of m in the HTML part of
+// emails.
+func emailColor(m renderedMessage) string {
+	switch {
+	case m.Kind == TransitionRecovered:
+		return "#40D24C"
+	case m.Status == Down:
+		return "#D24040"
+	}
+	return "#FFAC3B"
+}
+
+// Notify implements Notifier, keeping the alerts of e in
+// memory.
+func (e Email) Notify(results []Result) error {
+	return e.NotifyState(results, defaultNotifierState)
+}
+
+// NotifyState implements StatefulNotifier. Like Slack, it
+// tells about each endpoint that starts failing, goes from
+// degraded to down, is due a reminder or recovers, but in a
+// single email.
+func (e Email) NotifyState(results []Result, state NotifierState) error {
+	return e.NotifyContext(context.Background(), results, state)
+}
+
+// NotifyContext implements ContextNotifier. It is like
+// NotifyState, but gives up on the server once ctx is done.
+func (e Email) NotifyContext(ctx context.Context, results []Result, state NotifierState) error {
+	key := notifierKey("email", e.Host, e.From, strings.Join(e.To, ","))
+	return e.notifyBatch(ctx, state, key, results, func(ts []Transition) error {
+		return e.sendTransitions(ctx, ts)
+	})
+}
+
+// sendTransitions sends an email about ts, until ctx is
+// done.
+func (e Email) sendTransitions(ctx context.Context, ts []Transition) error {
+	var data emailData
+	for _, t := range ts {
+		title, body, err := e.render(t)
+		if err != nil {
+			return fmt.Errorf("email: %v", err)
+		}
+		data.Messages = append(data.Messages, renderedMessage{newMessage(t), title, body})
+	}
+	msg, err := e.message(data)
+	if err != nil {
+		return fmt.Errorf("email: %v", err)
+	}
+	log.Printf("email: sending %d transitions to %s", len(ts), strings.Join(e.To, ", "))
+	if err := e.send(ctx, msg); err != nil {
+		return fmt.Errorf("email: %v", err)
+	}
+	return nil
+}
+
+// message returns the email about data, with its headers.
+func (e Email) message(data emailData) ([]byte, error) {
+	subject, err := executeTemplate("subject_template", e.SubjectTemplate, DefaultSubjectTemplate, data)
+	if err != nil {
+		return nil, err
+	}
+	subject = strings.Join(strings.Fields(subject), " ")
+
+	var text strings.Builder
+	for i, m := range data.Messages {
+		if i > 0 {
+			text.WriteString("\r\n\r\n")
+		}
+		text.WriteString(m.Title + "\r\n\r\n" + m.Body)
+	}
+	var html bytes.Buffer
+	if err := emailHTML.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", text.String()},
+		{"text/html; charset=utf-8", html.String()},
+	} {
+		pw, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qw := quotedprintable.NewWriter(pw)
+		if _, err := qw.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qw.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", e.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(e.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&msg, "Message-ID: %s\r\n", e.messageID())
+	fmt.Fprintf(&msg, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&msg, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
+
+// messageID returns a new Message-ID for an email of e, in
+// the domain of its sender, such as
+// <1571234567890123456.1a2b3c4d5e6f7a8b@example.com>.
+func (e Email) messageID() string {
+	domain := e.Host
+	if addr, err := mail.ParseAddress(e.From); err == nil {
+		if i := strings.LastIndex(addr.Address, "@"); i >= 0 {
+			domain = addr.Address[i+1:]
+		}
+	}
+	var b [8]byte
+	rand.Read(b[:])
+	return fmt.Sprintf("<%d.%s@%s>", time.Now().UnixNano(), hex.EncodeToString(b[:]), domain)
+}
+
+// send sends msg to the recipients of e through its server,
+// waiting for it until ctx is done or e.Timeout elapses,
+// whichever comes first.
+func (e Email) send(ctx context.Context, msg []byte) error {
+	timeout := e.Timeout
+	if timeout <= 0 {
+		timeout = DefaultEmailTimeout
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	addr := net.JoinHostPort(e.Host, strconv.Itoa(e.port()))
+	dialer := &net.Dialer{Deadline: deadline}
+	tlsConfig := &tls.Config{ServerName: e.Host}
+	if e.tlsConfig != nil {
+		tlsConfig = e.tlsConfig
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(deadline)
+	// a canceled ctx, which may have no deadline, interrupts
+	// the exchange with the server
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+	if e.TLS == "tls" {
+		tc := tls.Client(conn, tlsConfig)
+		if err := tc.Handshake(); err != nil {
+			conn.Close()
+			return err
+		}
+		conn = tc
+	}
+	c, err := smtp.NewClient(conn, e.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer c.Close()
+
+	if e.TLS == "" || e.TLS == "starttls" {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if e.Username != "" {
+		username, err := envsubst.EvalEnv(e.Username)
+		if err != nil {
+			return fmt.Errorf("username: %v", err)
+		}
+		password, err := envsubst.EvalEnv(e.Password)
+		if err != nil {
+			return fmt.Errorf("password: %v", err)
+		}
+		var auth smtp.Auth = smtp.PlainAuth("", username, password, e.Host)
+		if e.Auth == "login" {
+			auth = loginAuth{e.Host, username, password}
+		}
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(e.From); err != nil {
+		return err
+	}
+	for _, to := range e.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// port returns the port of the server of e.
+func (e Email) port() int {
+	switch {
+	case e.Port != 0:
+		return e.Port
+	case e.TLS == "tls":
+		return 465
+	case e.TLS == "none":
+		return 25
+	}
+	return 587
+}
+
+// validate returns an error if e is misconfigured.
+func (e Email) validate() error {
+	switch {
+	case e.Host == "":
+		return fmt.Errorf("email: no host configured")
+	case e.From == "":
+		return fmt.Errorf("email: no sender (from) configured")
+	case len(e.To) == 0:
+		return fmt.Errorf("email: no recipients (to) configured")
+	}
+	switch e.TLS {
+	case "", "starttls", "tls", "none":
+	default:
+		return fmt.Errorf("email: invalid tls %q; want starttls, tls or none", e.TLS)
+	}
+	if e.Username != "" && e.TLS == "none" && !isLocalhost(e.Host) {
+		return fmt.Errorf("email: username set with tls none; credentials are only sent over TLS, or to localhost")
+	}
+	switch e.Auth {
+	case "", "plain", "login":
+	default:
+		return fmt.Errorf("email: invalid auth %q; want plain or login", e.Auth)
+	}
+	if err := e.NotifierOptions.validate(); err != nil {
+		return fmt.Errorf("email: %v", err)
+	}
+	if e.SubjectTemplate != "" {
+		data := emailData{Messages: []renderedMessage{{Message: sampleMessage()}}}
+		if _, err := executeTemplate("subject_template", e.SubjectTemplate, "", data); err != nil {
+			return fmt.Errorf("email: %v", err)
+		}
+	}
+	return nil
+}
+
+// loginAuth is the LOGIN authentication mechanism, which
+// smtp doesn't provide. Like smtp.PlainAuth, it only sends
+// credentials over TLS, or to localhost.
+type loginAuth struct {
+	host, username, password string
+}
+
+func (a loginAuth) Start(server *smtp.ServerInfo) (string, []byte, error) {
+	if !server.TLS && !isLocalhost(server.Name) {
+		return "", nil, errors.New("unencrypted connection")
+	}
+	if server.Name != a.host {
+		return "", nil, errors.New("wrong host name")
+	}
+	return "LOGIN", nil, nil
+}
+
+func (a loginAuth) Next(fromServer []byte, more bool) ([]byte, error) {
+	if !more {
+		return nil, nil
+	}
+	prompt := strings.ToLower(string(fromServer))
+	switch {
+	case strings.Contains(prompt, "user"):
+		return []byte(a.username), nil
+	case strings.Contains(prompt, "pass"):
+		return []byte(a.password), nil
+	}
+	return nil, fmt.Errorf("unexpected LOGIN challenge %q", fromServer)
+}
+
+// isLocalhost returns whether host is the local host, to
+// which credentials may be sent without TLS.
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1"
+}
@@ -0,0 +1,384 @@
+package checkup
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// smtpServer is a fake SMTP server that records the
+// commands and messages it receives.
+type smtpServer struct {
+	ln        net.Listener
+	tlsConfig *tls.Config
+	mu        sync.Mutex
+	commands  []string
+	messages  []string
+}
+
+// newSMTPServer starts an smtpServer. If config is set, the
+// server offers STARTTLS with it, or, if implicit is true,
+// only accepts TLS connections.
+func newSMTPServer(t *testing.T, config *tls.Config, implicit bool) *smtpServer {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &smtpServer{ln: ln, tlsConfig: config}
+	if implicit {
+		s.ln, s.tlsConfig = tls.NewListener(ln, config), nil
+	}
+	ln = s.ln
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *smtpServer) port() int {
+	return s.ln.Addr().(*net.TCPAddr).Port
+}
+
+func (s *smtpServer) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+	readLine := func() (string, bool) {
+		line, err := r.ReadString('\n')
+		return strings.TrimRight(line, "\r\n"), err == nil
+	}
+	reply("220 localhost ESMTP")
+	for {
+		line, ok := readLine()
+		if !ok {
+			return
+		}
+		s.mu.Lock()
+		s.commands = append(s.commands, line)
+		s.mu.Unlock()
+		cmd := strings.ToUpper(strings.Fields(line + " ")[0])
+		switch {
+		case cmd == "EHLO":
+			reply("250-localhost")
+			if _, ok := conn.(*tls.Conn); !ok && s.tlsConfig != nil {
+				reply("250-STARTTLS")
+			}
+			reply("250 AUTH PLAIN LOGIN")
+		case cmd == "STARTTLS":
+			reply("220 Ready to start TLS")
+			conn = tls.Server(conn, s.tlsConfig)
+			r = bufio.NewReader(conn)
+		case line == "AUTH LOGIN":
+			reply("334 " + base64.StdEncoding.EncodeToString([]byte("Username:")))
+			user, _ := readLine()
+			reply("334 " + base64.StdEncoding.EncodeToString([]byte("Password:")))
+			pass, _ := readLine()
+			s.mu.Lock()
+			s.commands = append(s.commands, user, pass)
+			s.mu.Unlock()
+			reply("235 OK")
+		case cmd == "AUTH":
+			reply("235 OK")
+		case cmd == "DATA":
+			reply("354 Go ahead")
+			var msg strings.Builder
+			for {
+				l, ok := readLine()
+				if !ok || l == "." {
+					break
+				}
+				msg.WriteString(strings.TrimPrefix(l, ".") + "\r\n")
+			}
+			s.mu.Lock()
+			s.messages = append(s.messages, msg.String())
+			s.mu.Unlock()
+			reply("250 OK")
+		case cmd == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestEmail(t *testing.T) {
+	srv := newSMTPServer(t, nil, false)
+	defer srv.ln.Close()
+
+	email := Email{
+		Host:     "127.0.0.1",
+		Port:     srv.port(),
+		TLS:      "none",
+		Username: "checkup",
+		Password: "s3cr3t",
+		From:     "checkup@example.com",
+		To:       []string{"ops@example.com", "dev@example.com"},
+	}
+	state := &memoryNotifierState{alerts: make(map[string]Alert)}
+	web := Result{Title: "Web", Endpoint: "https://example.com", Timestamp: Timestamp(), Down: true}
+	api := Result{Title: "API", Endpoint: "https://api.example.com", Timestamp: Timestamp(), Degraded: true}
+	for i := 0; i < 2; i++ {
+		if err := email.NotifyState([]Result{web, api}, state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	srv.mu.Lock()
+	if len(srv.messages) != 1 {
+		t.Fatalf("Expected both transitions in a single email, got %d emails", len(srv.messages))
+	}
+	commands := strings.Join(srv.commands, "\n")
+	for _, want := range []string{"AUTH PLAIN", "RCPT TO:<ops@example.com>", "RCPT TO:<dev@example.com>"} {
+		if !strings.Contains(commands, want) {
+			t.Errorf("Expected %q in commands, got:\n%s", want, commands)
+		}
+	}
+	msg, err := mail.ReadMessage(strings.NewReader(srv.messages[0]))
+	srv.mu.Unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("Subject"); got != "2 endpoints changed status" {
+		t.Errorf("Unexpected subject %q", got)
+	}
+	if got := msg.Header.Get("To"); got != "ops@example.com, dev@example.com" {
+		t.Errorf("Unexpected recipients %q", got)
+	}
+	messageID := msg.Header.Get("Message-ID")
+	if !strings.HasPrefix(messageID, "<") || !strings.HasSuffix(messageID, "@example.com>") {
+		t.Errorf("Unexpected message ID %q", messageID)
+	}
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("Expected a multipart/alternative email, got %q (%v)", mediaType, err)
+	}
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for _, want := range []string{"text/plain", "text/html"} {
+		part, err := parts.NextPart()
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(part)
+		if !strings.HasPrefix(part.Header.Get("Content-Type"), want) {
+			t.Errorf("Expected a %s part, got %s", want, part.Header.Get("Content-Type"))
+		}
+		for _, title := range []string{"Web is down", "API is degraded"} {
+			if !strings.Contains(string(b), title) {
+				t.Errorf("Expected %q in the %s part, got:\n%s", title, want, b)
+			}
+		}
+	}
+
+	email.Auth = "login"
+	email.SubjectTemplate = `[checkup] {{range .Messages}}{{.Result.Title}} {{.Kind}} {{end}}`
+	web.Down, web.Healthy = false, true
+	if err := email.NotifyState([]Result{web, api}, state); err != nil {
+		t.Fatal(err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if len(srv.messages) != 2 {
+		t.Fatalf("Expected an email about the recovery, got %d emails", len(srv.messages))
+	}
+	login := base64.StdEncoding.EncodeToString([]byte("checkup"))
+	if !strings.Contains(strings.Join(srv.commands, "\n"), "AUTH LOGIN\n"+login) {
+		t.Errorf("Expected LOGIN authentication, got:\n%s", strings.Join(srv.commands, "\n"))
+	}
+	msg, err = mail.ReadMessage(strings.NewReader(srv.messages[1]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := msg.Header.Get("Subject"); got != "[checkup] Web recovered" {
+		t.Errorf("Unexpected subject %q", got)
+	}
+	if got := msg.Header.Get("Message-ID"); got == messageID {
+		t.Errorf("Expected a new message ID, got %q again", got)
+	}
+}
+
+func TestEmailTLS(t *testing.T) {
+	cert, err := tls.LoadX509KeyPair("testdata/server.pem", "testdata/key.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := ioutil.ReadFile("testdata/ca.pem")
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	if !roots.AppendCertsFromPEM(ca) {
+		t.Fatal("failed to parse the CA certificate")
+	}
+	serverConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	for _, mode := range []string{"starttls", "tls"} {
+		srv := newSMTPServer(t, serverConfig, mode == "tls")
+		email := Email{
+			Host:      "localhost",
+			Port:      srv.port(),
+			TLS:       mode,
+			Username:  "checkup",
+			Password:  "s3cr3t",
+			From:      "checkup@example.com",
+			To:        []string{"ops@example.com"},
+			tlsConfig: &tls.Config{ServerName: "localhost", RootCAs: roots},
+		}
+		down := Result{Title: "Web", Timestamp: Timestamp(), Down: true}
+		err := email.NotifyState([]Result{down}, &memoryNotifierState{alerts: make(map[string]Alert)})
+		srv.ln.Close()
+		if err != nil {
+			t.Errorf("%s: %v", mode, err)
+			continue
+		}
+		srv.mu.Lock()
+		commands := strings.Join(srv.commands, "\n")
+		if len(srv.messages) != 1 || !strings.Contains(commands, "AUTH PLAIN") {
+			t.Errorf("%s: Expected an authenticated email, got %d emails and commands:\n%s", mode, len(srv.messages), commands)
+		}
+		if got, want := strings.Contains(commands, "STARTTLS"), mode == "starttls"; got != want {
+			t.Errorf("%s: Expected STARTTLS to be used: %t, got commands:\n%s", mode, want, commands)
+		}
+		srv.mu.Unlock()
+	}
+
+	// the certificate of the server is verified
+	srv := newSMTPServer(t, serverConfig, true)
+	defer srv.ln.Close()
+	email := Email{Host: "localhost", Port: srv.port(), TLS: "tls", From: "checkup@example.com", To: []string{"ops@example.com"}}
+	down := Result{Title: "Web", Timestamp: Timestamp(), Down: true}
+	if err := email.NotifyState([]Result{down}, &memoryNotifierState{alerts: make(map[string]Alert)}); err == nil {
+		t.Error("Expected an error for a certificate signed by an unknown authority")
+	}
+}
+
+func TestEmailStartTLSRequired(t *testing.T) {
+	srv := newSMTPServer(t, nil, false)
+	defer srv.ln.Close()
+
+	email := Email{Host: "127.0.0.1", Port: srv.port(), From: "checkup@example.com", To: []string{"ops@example.com"}}
+	down := Result{Title: "Web", Timestamp: Timestamp(), Down: true}
+	err := email.NotifyState([]Result{down}, &memoryNotifierState{alerts: make(map[string]Alert)})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Expected an error without STARTTLS, got %v", err)
+	}
+}
+
+func TestEmailBadCredentials(t *testing.T) {
+	srv := newSMTPServer(t, nil, false)
+	defer srv.ln.Close()
+
+	email := Email{
+		Host:     "127.0.0.1",
+		Port:     srv.port(),
+		TLS:      "none",
+		Username: "checkup",
+		Password: "${SMTP_PASSWORD",
+		From:     "checkup@example.com",
+		To:       []string{"ops@example.com"},
+	}
+	down := Result{Title: "Web", Timestamp: Timestamp(), Down: true}
+	err := email.NotifyState([]Result{down}, &memoryNotifierState{alerts: make(map[string]Alert)})
+	if err == nil || !strings.Contains(err.Error(), "password") {
+		t.Errorf("Expected an error expanding the password, got %v", err)
+	}
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if commands := strings.Join(srv.commands, "\n"); strings.Contains(commands, "AUTH") {
+		t.Errorf("Expected no authentication, got:\n%s", commands)
+	}
+}
+
+func TestEmailContext(t *testing.T) {
+	// a server that never greets
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				ioutil.ReadAll(conn)
+				conn.Close()
+			}()
+		}
+	}()
+
+	email := Email{
+		Host:    "127.0.0.1",
+		Port:    ln.Addr().(*net.TCPAddr).Port,
+		TLS:     "none",
+		From:    "checkup@example.com",
+		To:      []string{"ops@example.com"},
+		Timeout: time.Minute,
+	}
+	down := Result{Title: "Web", Timestamp: Timestamp(), Down: true}
+	for _, cancel := range []bool{false, true} {
+		ctx, stop := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		if cancel {
+			ctx, stop = context.WithCancel(context.Background())
+			time.AfterFunc(100*time.Millisecond, stop)
+		}
+		start := time.Now()
+		err := email.NotifyContext(ctx, []Result{down}, &memoryNotifierState{alerts: make(map[string]Alert)})
+		stop()
+		if err == nil {
+			t.Errorf("Expected an error once ctx is done (cancel=%v)", cancel)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("Expected to give up once ctx is done (cancel=%v), took %s", cancel, elapsed)
+		}
+	}
+}
+
+func TestEmailValidate(t *testing.T) {
+	valid := `"host":"smtp.example.com","from":"checkup@example.com","to":["ops@example.com"]`
+	for _, config := range []string{
+		`{"notifier":{"name":"email","from":"checkup@example.com","to":["ops@example.com"]}}`,
+		`{"notifier":{"name":"email","host":"smtp.example.com","from":"checkup@example.com"}}`,
+		`{"notifier":{"name":"email",` + valid + `,"tls":"ssl"}}`,
+		`{"notifier":{"name":"email",` + valid + `,"auth":"cram-md5"}}`,
+		`{"notifier":{"name":"email",` + valid + `,"subject_template":"{{.Title}}"}}`,
+		`{"notifier":{"name":"email",` + valid + `,"tls":"none","username":"checkup"}}`,
+	} {
+		var c Checkup
+		if err := json.Unmarshal([]byte(config), &c); err == nil {
+			t.Errorf("Expected an error for %s", config)
+		}
+	}
+	var c Checkup
+	config := `{"notifier":{"name":"email",` + valid + `,"tls":"tls","subject_template":"{{len .Messages}} alerts"}}`
+	if err := json.Unmarshal([]byte(config), &c); err != nil {
+		t.Fatalf("Expected a valid email notifier, got %v", err)
+	}
+	if port := c.Notifier.(Email).port(); port != 465 {
+		t.Errorf("Expected port 465 with TLS, got %d", port)
+	}
+	local := `{"notifier":{"name":"email","host":"localhost","from":"checkup@example.com","to":["ops@example.com"],"tls":"none","username":"checkup"}}`
+	if err := json.Unmarshal([]byte(local), &c); err != nil {
+		t.Errorf("Expected credentials to be allowed without TLS to localhost, got %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+package checkup
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Formats are the formats in which results can be written
+// with WriteResults.
+var Formats = []string{"text", "json", "junit", "tap"}
+
+// WriteResults writes results to w in format, which is one
+// of Formats:
+//
+//	text   the results as printed by Result.String
+//	json   a JSON array of the results
+//	junit  a JUnit XML test suite, with a test case per result
+//	tap    a Test Anything Protocol (version 13) stream
+//
+// In the junit and tap formats, results that are not
+// healthy fail, unless in maintenance, in which case they
+// are skipped.
+func WriteResults(w io.Writer, format string, results []Result) error {
+	switch format {
+	case "text":
+		return writeText(w, results)
+	case "json":
+		return writeResultsJSON(w, results)
+	case "junit":
+		return writeJUnit(w, results)
+	case "tap":
+		return writeTAP(w, results)
+	}
+	return fmt.Errorf("unknown format %q; want one of %s", format, strings.Join(Formats, ", "))
+}
+
+func writeText(w io.Writer, results []Result) error {
+	for _, result := range results {
+		if _, err := fmt.Fprintln(w, result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeResultsJSON(w io.Writer, results []Result) error {
+	if results == nil {
+		results = []Result{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+type junitTestSuites struct {
+	XMLName xml.Name         `xml:"testsuites"`
+	Suites  []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut *junitOutput  `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",cdata"`
+}
+
+type junitOutput struct {
+	Text string `xml:",cdata"`
+}
+
+func writeJUnit(w io.Writer, results []Result) error {
+	suite := junitTestSuite{Name: "checkup", Tests: len(results), Cases: []junitTestCase{}}
+	var total time.Duration
+	var latest int64
+	for _, result := range results {
+		elapsed := attemptsTime(result)
+		total += elapsed
+		if result.Timestamp > latest {
+			latest = result.Timestamp
+		}
+		tc := junitTestCase{
+			Name:      result.Title,
+			ClassName: "checkup",
+			Time:      seconds(elapsed),
+		}
+		if result.Type != "" {
+			tc.ClassName += "." + result.Type
+		}
+		switch {
+		case result.Maintenance:
+			suite.Skipped++
+			tc.Skipped = &junitMessage{Message: resultSummary(result)}
+		case failed(result):
+			suite.Failures++
+			tc.Failure = &junitMessage{
+				Message: resultSummary(result),
+				Type:    string(result.Status()),
+				Text:    resultText(result),
+			}
+		default:
+			if text := resultText(result); text != "" {
+				tc.SystemOut = &junitOutput{Text: text}
+			}
+		}
+		suite.Cases = append(suite.Cases, tc)
+	}
+	suite.Time = seconds(total)
+	if latest > 0 {
+		suite.Timestamp = time.Unix(0, latest).UTC().Format("2006-01-02T15:04:05")
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(junitTestSuites{Suites: []junitTestSuite{suite}}); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// tapEscaper escapes the descriptions of TAP test points.
+var tapEscaper = strings.NewReplacer("#", "\\#", "\n", " ")
+
+func writeTAP(w io.Writer, results []Result) error {
+	var b strings.Builder
+	b.WriteString("TAP version 13\n")
+	fmt.Fprintf(&b, "1..%d\n", len(results))
+	for i, result := range results {
+		description := result.Title
+		if result.Endpoint != "" {
+			description += " (" + result.Endpoint + ")"
+		}
+		description = tapEscaper.Replace(description)
+		switch {
+		case result.Maintenance:
+			fmt.Fprintf(&b, "ok %d - %s # SKIP %s\n", i+1, description, resultSummary(result))
+		case failed(result):
+			fmt.Fprintf(&b, "not ok %d - %s\n", i+1, description)
+			b.WriteString("  ---\n")
+			fmt.Fprintf(&b, "  message: %q\n", resultSummary(result))
+			fmt.Fprintf(&b, "  severity: %s\n", result.Status())
+			if text := resultText(result); text != "" {
+				b.WriteString("  data: |\n")
+				for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+					b.WriteString("    " + line + "\n")
+				}
+			}
+			b.WriteString("  ...\n")
+		default:
+			fmt.Fprintf(&b, "ok %d - %s\n", i+1, description)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// failed returns whether r is neither healthy nor in
+// maintenance.
+func failed(r Result) bool {
+	return !r.Healthy && !r.Maintenance
+}
+
+// resultSummary returns a line describing the status of r
+// and its details, such as
+// "down: unexpected status code (status_code=503)".
+func resultSummary(r Result) string {
+	s := string(r.Status())
+	if notice := resultNotice(r); notice != "" {
+		s += ": " + notice
+	}
+	if len(r.Details) > 0 {
+		var details []string
+		for _, key := range detailKeys(r) {
+			details = append(details, fmt.Sprintf("%s=%v", key, r.Details[key]))
+		}
+		s += " (" + strings.Join(details, ", ") + ")"
+	}
+	return s
+}
+
+// resultText returns the endpoint, the errors of the
+// attempts and the details of r, one per line.
+func resultText(r Result) string {
+	var b strings.Builder
+	if r.Endpoint != "" {
+		fmt.Fprintf(&b, "endpoint: %s\n", r.Endpoint)
+	}
+	if loc := r.location(); loc != "" {
+		fmt.Fprintf(&b, "location: %s\n", loc)
+	}
+	for i, attempt := range r.Times {
+		if attempt.Error != "" {
+			fmt.Fprintf(&b, "attempt %d: %s\n", i+1, attempt.Error)
+		}
+	}
+	for _, key := range detailKeys(r) {
+		fmt.Fprintf(&b, "%s: %v\n", key, r.Details[key])
+	}
+	return b.String()
+}
+
+// firstError returns the error of the first failed
+// attempt of r, if any.
+func firstError(r Result) string {
+	for _, attempt := range r.Times {
+		if attempt.Error != "" {
+			return attempt.Error
+		}
+	}
+	return ""
+}
+
+// detailKeys returns the keys of the details of r, sorted.
+func detailKeys(r Result) []string {
+	keys := make([]string, 0, len(r.Details))
+	for key := range r.Details {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// attemptsTime returns the total round trip time of the
+// attempts of r.
+func attemptsTime(r Result) time.Duration {
+	var total time.Duration
+	for _, attempt := range r.Times {
+		total += attempt.RTT
+	}
+	return total
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3f", d.Seconds())
+}
@@ -0,0 +1,105 @@
+package checkup
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+	"time"
+)
+
+var formatResults = []Result{
+	{Title: "Web", Endpoint: "https://example.com/#top", Type: "http", Healthy: true,
+		Times: Attempts{{RTT: 100 * time.Millisecond}, {RTT: 200 * time.Millisecond}}},
+	{Title: "API", Endpoint: "https://api.example.com", Type: "http", Down: true,
+		Times:   Attempts{{RTT: 50 * time.Millisecond, Error: "unexpected status code"}},
+		Details: map[string]interface{}{"status_code": 503}},
+	{Title: "DB", Endpoint: "db:5432", Type: "tcp", Degraded: true, Notice: "median round trip time exceeded threshold (10ms)"},
+	{Title: "Backup", Endpoint: "s3://bucket", Type: "backup:s3", Down: true, Maintenance: true},
+}
+
+func TestWriteResultsJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, "json", formatResults); err != nil {
+		t.Fatal(err)
+	}
+	var results []Result
+	if err := json.Unmarshal(buf.Bytes(), &results); err != nil {
+		t.Fatalf("Expected valid JSON, got %v: %s", err, buf.String())
+	}
+	if len(results) != len(formatResults) || results[1].Title != "API" || !results[1].Down {
+		t.Errorf("Expected the results, got %+v", results)
+	}
+
+	buf.Reset()
+	if err := WriteResults(&buf, "json", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.TrimSpace(buf.String()); got != "[]" {
+		t.Errorf("Expected an empty array without results, got %s", got)
+	}
+}
+
+func TestWriteResultsJUnit(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, "junit", formatResults); err != nil {
+		t.Fatal(err)
+	}
+	var suites junitTestSuites
+	if err := xml.Unmarshal(buf.Bytes(), &suites); err != nil {
+		t.Fatalf("Expected valid XML, got %v: %s", err, buf.String())
+	}
+	if len(suites.Suites) != 1 {
+		t.Fatalf("Expected 1 test suite, got %d", len(suites.Suites))
+	}
+	suite := suites.Suites[0]
+	if suite.Tests != 4 || suite.Failures != 2 || suite.Skipped != 1 {
+		t.Errorf("Expected 4 tests, 2 failures and 1 skipped, got %d, %d and %d", suite.Tests, suite.Failures, suite.Skipped)
+	}
+	web, api, db, backup := suite.Cases[0], suite.Cases[1], suite.Cases[2], suite.Cases[3]
+	if web.Failure != nil || web.ClassName != "checkup.http" || web.Time != "0.300" {
+		t.Errorf("Expected Web to pass in 0.300s, got %+v", web)
+	}
+	if api.Failure == nil || api.Failure.Type != "down" {
+		t.Fatalf("Expected API to fail as down, got %+v", api)
+	}
+	if want := "down: unexpected status code (status_code=503)"; api.Failure.Message != want {
+		t.Errorf("Expected failure message %q, got %q", want, api.Failure.Message)
+	}
+	if !strings.Contains(api.Failure.Text, "status_code: 503") {
+		t.Errorf("Expected the details in the failure, got %q", api.Failure.Text)
+	}
+	if db.Failure == nil || db.Failure.Type != "degraded" || !strings.Contains(db.Failure.Message, "threshold") {
+		t.Errorf("Expected DB to fail as degraded, got %+v", db.Failure)
+	}
+	if backup.Failure != nil || backup.Skipped == nil {
+		t.Errorf("Expected Backup to be skipped, got %+v", backup)
+	}
+}
+
+func TestWriteResultsTAP(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, "tap", formatResults); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"TAP version 13\n1..4\n",
+		"ok 1 - Web (https://example.com/\\#top)\n",
+		"not ok 2 - API (https://api.example.com)\n  ---\n  message: \"down: unexpected status code (status_code=503)\"\n  severity: down\n",
+		"    status_code: 503\n",
+		"not ok 3 - DB (db:5432)\n",
+		"ok 4 - Backup (s3://bucket) # SKIP maintenance\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected TAP output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteResultsUnknownFormat(t *testing.T) {
+	if err := WriteResults(&bytes.Buffer{}, "yaml", formatResults); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
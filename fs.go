@@ -2,12 +2,17 @@ package checkup
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"time"
 )
 
+func init() {
+	RegisterStorage("fs", func() Storage { return FS{} })
+}
+
 const indexName = "index.json"
 
 // FS is a way to store checkup results on the local filesystem.
@@ -72,8 +77,13 @@ func (fs FS) Fetch(name string) ([]Result, error) {
 
 // Store stores results on filesystem according to the configuration in fs.
 func (fs FS) Store(results []Result) error {
+	return fs.StoreCheckFile(*GenerateFilename(), time.Now().UnixNano(), results)
+}
+
+// StoreCheckFile stores results on filesystem in the check
+// file name, with the given timestamp in the index.
+func (fs FS) StoreCheckFile(name string, timestamp int64, results []Result) error {
 	// Write results to a new file
-	name := *GenerateFilename()
 	f, err := os.Create(filepath.Join(fs.Dir, name))
 	if err != nil {
 		return err
@@ -91,12 +101,81 @@ func (fs FS) Store(results []Result) error {
 	}
 
 	// Add new file to index
-	index[name] = time.Now().UnixNano()
+	index[name] = timestamp
 
 	// Write new index
 	return fs.writeIndex(index)
 }
 
+// FetchDocument reads the document name from filesystem.
+func (fs FS) FetchDocument(name string) ([]byte, error) {
+	return ioutil.ReadFile(filepath.Join(fs.Dir, name))
+}
+
+// StoreDocument writes the document name to filesystem.
+// It replaces the previous document at once, so that it is
+// never read partly written.
+func (fs FS) StoreDocument(name string, contents []byte) error {
+	return writeFileAtomic(filepath.Join(fs.Dir, name), contents, 0644)
+}
+
+// documentLockTimeout is how long to wait for the lock of a
+// document, and documentLockStale is how old a lock is
+// deemed left behind by a process that died.
+const (
+	documentLockTimeout = 10 * time.Second
+	documentLockStale   = time.Minute
+)
+
+// lockDocument implements documentLocker with a lock file
+// next to the document, so that several processes don't
+// change it at the same time.
+func (fs FS) lockDocument(name string) (func(), error) {
+	path := filepath.Join(fs.Dir, name+".lock")
+	deadline := time.Now().Add(documentLockTimeout)
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("locking %s: %v", name, err)
+		}
+		if info, err := os.Stat(path); err == nil && time.Since(info.ModTime()) > documentLockStale {
+			os.Remove(path)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("locking %s: %s is held by another process", name, path)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// writeFileAtomic writes contents to a temporary file in
+// the directory of path, then renames it to path, so that
+// path is never left partly written.
+func writeFileAtomic(path string, contents []byte, perm os.FileMode) error {
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path))
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(contents); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
 // Maintain deletes check files that are older than fs.CheckExpiry.
 func (fs FS) Maintain() error {
 	if fs.CheckExpiry == 0 {
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strings"
 	"time"
@@ -14,6 +15,10 @@ import (
 	"golang.org/x/oauth2"
 )
 
+func init() {
+	RegisterStorage("github", func() Storage { return &GitHub{} })
+}
+
 var errFileNotFound = fmt.Errorf("file not found on github")
 
 // GitHub is a way to store checkup results in a GitHub repository.
@@ -220,13 +225,20 @@ func (gh *GitHub) writeIndex(index map[string]int64, sha string) error {
 
 // Store stores results in the Git repo & updates the index.
 func (gh *GitHub) Store(results []Result) error {
+	return gh.StoreCheckFile(*GenerateFilename(), time.Now().UnixNano(), results)
+}
+
+// StoreCheckFile stores results in the Git repo in the
+// check file name, with the given timestamp in the index.
+func (gh *GitHub) StoreCheckFile(name string, timestamp int64, results []Result) error {
 	// Write results to a new file
-	name := *GenerateFilename()
 	contents, err := json.Marshal(results)
 	if err != nil {
 		return err
 	}
-	err = gh.writeFile(name, "", contents)
+	if err := gh.writeFile(name, "", contents); err != nil {
+		return err
+	}
 
 	// Read current index file
 	index, indexSHA, err := gh.readIndex()
@@ -235,7 +247,7 @@ func (gh *GitHub) Store(results []Result) error {
 	}
 
 	// Add new file to index
-	index[name] = time.Now().UnixNano()
+	index[name] = timestamp
 
 	// Write new index
 	return gh.writeIndex(index, indexSHA)
@@ -258,6 +270,24 @@ func (gh *GitHub) GetIndex() (map[string]int64, error) {
 	return m, e
 }
 
+// FetchDocument reads the document name from the Git repo.
+func (gh *GitHub) FetchDocument(name string) ([]byte, error) {
+	contents, _, err := gh.readFile(name)
+	if err == errFileNotFound {
+		return nil, os.ErrNotExist
+	}
+	return contents, err
+}
+
+// StoreDocument writes the document name to the Git repo.
+func (gh *GitHub) StoreDocument(name string, contents []byte) error {
+	_, sha, err := gh.readFile(name)
+	if err != nil && err != errFileNotFound {
+		return err
+	}
+	return gh.writeFile(name, sha, contents)
+}
+
 // Maintain deletes check files that are older than gh.CheckExpiry.
 func (gh *GitHub) Maintain() error {
 	if gh.CheckExpiry == 0 {
@@ -1,10 +1,10 @@
 module github.com/Sparklane/checkup
 
-go 1.12
+go 1.16
 
 require (
 	github.com/ashwanthkumar/slack-go-webhook v0.0.0-20181208062437-4a19b1a876b7
-	github.com/aws/aws-sdk-go v1.23.22
+	github.com/aws/aws-sdk-go v1.27.0
 	github.com/drone/envsubst v1.0.2
 	github.com/elazarl/goproxy v0.0.0-20190711103511-473e67f1d7d2 // indirect
 	github.com/elazarl/goproxy/ext v0.0.0-20190711103511-473e67f1d7d2 // indirect
@@ -19,8 +19,8 @@ require (
 	github.com/miekg/dns v1.1.16
 	github.com/moul/http2curl v1.0.0 // indirect
 	github.com/parnurzeal/gorequest v0.2.15 // indirect
-	github.com/pkg/errors v0.8.1 // indirect
-	github.com/smartystreets/goconvey v0.0.0-20190731233626-505e41936337 // indirect
+	github.com/prometheus/client_golang v1.10.0
+	github.com/robfig/cron/v3 v3.0.1
 	github.com/spf13/cobra v0.0.5
 	golang.org/x/oauth2 v0.0.0-20190604053449-0f29369cfe45
 )
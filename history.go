@@ -0,0 +1,157 @@
+package checkup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// Timeline is the history of an endpoint, as the periods
+// during which its status stayed the same.
+type Timeline struct {
+	Title    string   `json:"title"`
+	Endpoint string   `json:"endpoint"`
+	Location string   `json:"location,omitempty"`
+	Periods  []Period `json:"periods"`
+}
+
+// Period is a period during which the status of an
+// endpoint stayed the same. It starts at the first check
+// that found this status, and ends at the first check that
+// found another one; the latest period ends at the latest
+// check and is Ongoing.
+type Period struct {
+	Status  StatusText `json:"status"`
+	From    time.Time  `json:"from"`
+	To      time.Time  `json:"to"`
+	Checks  int        `json:"checks"`
+	Ongoing bool       `json:"ongoing,omitempty"`
+
+	// Notice is the first notice, or error, of the checks
+	// of the period.
+	Notice string `json:"notice,omitempty"`
+}
+
+// Duration returns how long p lasted.
+func (p Period) Duration() time.Duration {
+	return p.To.Sub(p.From)
+}
+
+// History reads the results checked since the given time
+// in reader, and returns the timeline of each endpoint,
+// sorted by title. If about is not empty, only the
+// timeline of the endpoint with that title (case-
+// insensitively) is returned. Results of each location
+// have their own timeline.
+func History(reader StorageReader, about string, since time.Time) ([]Timeline, error) {
+	index, err := reader.GetIndex()
+	if err != nil {
+		return nil, fmt.Errorf("reading index: %v", err)
+	}
+	byEndpoint := make(map[string][]Result)
+	for name, ts := range index {
+		// check files are stored after their results
+		if ts < since.UnixNano() {
+			continue
+		}
+		results, err := reader.Fetch(name)
+		if err != nil {
+			return nil, fmt.Errorf("fetching %s: %v", name, err)
+		}
+		for _, result := range results {
+			if result.Timestamp < since.UnixNano() {
+				continue
+			}
+			if about != "" && !strings.EqualFold(result.Title, about) {
+				continue
+			}
+			key := strings.ToLower(result.Title) + "\x00" + result.location()
+			byEndpoint[key] = append(byEndpoint[key], result)
+		}
+	}
+
+	timelines := make([]Timeline, 0, len(byEndpoint))
+	for _, results := range byEndpoint {
+		timelines = append(timelines, timelineOf(results))
+	}
+	sort.Slice(timelines, func(i, j int) bool {
+		ti, tj := strings.ToLower(timelines[i].Title), strings.ToLower(timelines[j].Title)
+		if ti != tj {
+			return ti < tj
+		}
+		return timelines[i].Location < timelines[j].Location
+	})
+	return timelines, nil
+}
+
+// timelineOf returns the timeline of the results of an
+// endpoint.
+func timelineOf(results []Result) Timeline {
+	sort.Slice(results, func(i, j int) bool { return results[i].Timestamp < results[j].Timestamp })
+	latest := results[len(results)-1]
+	t := Timeline{Title: latest.Title, Endpoint: latest.Endpoint, Location: latest.location()}
+	for _, result := range results {
+		ts := time.Unix(0, result.Timestamp)
+		status := result.Status()
+		if n := len(t.Periods); n > 0 && t.Periods[n-1].Status == status {
+			p := &t.Periods[n-1]
+			p.To = ts
+			p.Checks++
+			if p.Notice == "" {
+				p.Notice = resultNotice(result)
+			}
+			continue
+		}
+		if n := len(t.Periods); n > 0 {
+			t.Periods[n-1].To = ts
+		}
+		t.Periods = append(t.Periods, Period{
+			Status: status,
+			From:   ts,
+			To:     ts,
+			Checks: 1,
+			Notice: resultNotice(result),
+		})
+	}
+	t.Periods[len(t.Periods)-1].Ongoing = true
+	return t
+}
+
+// resultNotice returns the notice of r, or the error of
+// its first failed attempt.
+func resultNotice(r Result) string {
+	if r.Notice != "" {
+		return r.Notice
+	}
+	return firstError(r)
+}
+
+// String returns the periods of t, one per line, with
+// their start time in the local time zone, status,
+// duration and notice.
+func (t Timeline) String() string {
+	var b strings.Builder
+	tw := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	for _, p := range t.Periods {
+		duration := p.Duration().Round(time.Second).String()
+		if p.Ongoing {
+			duration += "+"
+		}
+		fmt.Fprintf(tw, "  %s\t%s\t%s\t%s\n", p.From.Local().Format(time.RFC3339), p.Status, duration, p.Notice)
+	}
+	tw.Flush()
+
+	s := fmt.Sprintf("== %s - %s", t.Title, t.Endpoint)
+	if t.Location != "" {
+		s += fmt.Sprintf(" (%s)", t.Location)
+	}
+	s += "\n"
+	for _, line := range strings.SplitAfter(b.String(), "\n") {
+		if line != "" {
+			s += strings.TrimRight(line, " \n") + "\n"
+		}
+	}
+	return s
+}
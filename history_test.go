@@ -0,0 +1,68 @@
+package checkup
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	storage := FS{Dir: dir}
+
+	start := time.Now().Add(-time.Hour).Truncate(time.Second)
+	at := func(minutes int) int64 { return start.Add(time.Duration(minutes) * time.Minute).UnixNano() }
+	for _, results := range [][]Result{
+		{{Title: "API", Timestamp: at(-120), Down: true}, {Title: "Web", Timestamp: at(0), Healthy: true}},
+		{{Title: "API", Timestamp: at(0), Healthy: true}, {Title: "Web", Timestamp: at(0), Healthy: true, Node: "paris"}},
+		{{Title: "API", Timestamp: at(10), Down: true, Times: Attempts{{Error: "connection refused"}}}},
+		{{Title: "API", Timestamp: at(20), Down: true, Notice: "unreachable"}},
+		{{Title: "API", Timestamp: at(30), Healthy: true}},
+		{{Title: "API", Timestamp: at(40), Healthy: true}},
+	} {
+		if err := storage.Store(results); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	timelines, err := History(storage, "", start.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timelines) != 3 || timelines[0].Title != "API" || timelines[1].Location != "" || timelines[2].Location != "paris" {
+		t.Fatalf("Expected timelines of API, Web and Web in paris, got %+v", timelines)
+	}
+
+	api := timelines[0].Periods
+	if len(api) != 3 {
+		t.Fatalf("Expected 3 periods of API, got %+v", api)
+	}
+	if api[0].Status != Healthy || api[0].Duration() != 10*time.Minute || api[0].Ongoing {
+		t.Errorf("Expected API to be healthy for 10m, got %+v", api[0])
+	}
+	if api[1].Status != Down || api[1].Duration() != 20*time.Minute || api[1].Checks != 2 || api[1].Notice != "connection refused" {
+		t.Errorf("Expected API to be down for 20m, got %+v", api[1])
+	}
+	if api[2].Status != Healthy || api[2].Duration() != 10*time.Minute || !api[2].Ongoing {
+		t.Errorf("Expected API to be healthy since, got %+v", api[2])
+	}
+
+	s := timelines[0].String()
+	if !strings.HasPrefix(s, "== API - \n") || !strings.Contains(s, "  down     20m0s   connection refused\n") || !strings.HasSuffix(s, "  healthy  10m0s+\n") {
+		t.Errorf("Unexpected timeline:\n%s", s)
+	}
+
+	timelines, err = History(storage, "web", start.Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timelines) != 2 || timelines[0].Title != "Web" {
+		t.Errorf("Expected only the timelines of Web, got %+v", timelines)
+	}
+}
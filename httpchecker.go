@@ -1,18 +1,22 @@
 package checkup
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io/ioutil"
 	"net"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"github.com/drone/envsubst"
 )
 
+func init() {
+	RegisterChecker("http", func() Checker { return HTTPChecker{} })
+}
+
 // HTTPChecker implements a Checker for HTTP endpoints.
 type HTTPChecker struct {
 	// Name is the name of the endpoint.
@@ -69,12 +73,17 @@ type HTTPChecker struct {
 	// occurs between retries.
 	RetrySpacing time.Duration `json:"retry_spacing,omitempty"`
 
-	// IgnoreTimes times when down check result should be ignored
-	// because of recurring maintenance for example
+	// IgnoreTimes are times of day ("15:04:05"), in the checker's
+	// Timezone, at which recurring maintenance starts. Results
+	// of checks during maintenance have the Maintenance status.
+	//
+	// Deprecated: use a MaintenanceWindow with At and Duration.
 	IgnoreTimes []string `json:"ignore_times,omitempty"`
 
-	// IgnoreDuration duration when down check result should be ignored
-	// because of recurring maintenance for example
+	// IgnoreDuration is how long maintenance lasts after each
+	// of IgnoreTimes.
+	//
+	// Deprecated: use a MaintenanceWindow with At and Duration.
 	IgnoreDuration time.Duration `json:"ignore_duration,omitempty"`
 
 	// Insecure TLS Skip Verify.
@@ -95,11 +104,25 @@ type HTTPChecker struct {
 
 	// Set degraded instead of down
 	Degraded bool `json:"degraded,omitempty"`
+
+	// CheckerOptions are the settings common to all checkers.
+	CheckerOptions
+}
+
+// Title returns the endpoint name of c.
+func (c HTTPChecker) Title() string {
+	return c.Name
 }
 
 // Check performs checks using c according to its configuration.
 // An error is only returned if there is a configuration error.
 func (c HTTPChecker) Check() (Result, error) {
+	return c.CheckContext(context.Background())
+}
+
+// CheckContext performs checks like Check, aborting pending
+// requests and attempts once ctx is done.
+func (c HTTPChecker) CheckContext(ctx context.Context) (Result, error) {
 	if c.Attempts < 1 {
 		c.Attempts = 1
 	}
@@ -112,65 +135,79 @@ func (c HTTPChecker) Check() (Result, error) {
 	if c.UpStatus == 0 {
 		c.UpStatus = http.StatusOK
 	}
+	if err := c.validate(); err != nil {
+		return Result{}, err
+	}
 
 	result := Result{Title: c.Name, Endpoint: c.URL, Timestamp: Timestamp()}
 
-	result.Times = c.doChecks()
+	result.Times, result.Details = c.doChecks(ctx)
 
 	return c.conclude(result), nil
 }
 
-// doChecks executes and returns each attempt.
-func (c HTTPChecker) doChecks() Attempts {
+// doChecks executes and returns each attempt, along with
+// the details of the last response received.
+func (c HTTPChecker) doChecks(ctx context.Context) (Attempts, map[string]interface{}) {
 	checks := make(Attempts, c.Attempts)
+	details := make(map[string]interface{})
 	for i := 0; i < c.Attempts; i++ {
+		if err := ctx.Err(); err != nil {
+			checks[i].Error = attemptError(ctx, err)
+			continue
+		}
 		start := time.Now()
 		// check
-		err := c.doCheck()
+		err := c.doCheck(ctx, details)
 		if err != nil {
 			// retries
 			if c.Retries > 0 {
-				err = c.doRetries()
+				err = c.doRetries(ctx, details)
 				if err != nil {
-					checks[i].Error = err.Error()
+					checks[i].Error = attemptError(ctx, err)
 				} else {
 					checks[i].RTT = time.Since(start)
 				}
 			} else {
-				checks[i].Error = err.Error()
+				checks[i].Error = attemptError(ctx, err)
 			}
 		} else {
 			checks[i].RTT = time.Since(start)
 		}
 		if c.AttemptSpacing > 0 {
-			time.Sleep(c.AttemptSpacing)
+			sleep(ctx, c.AttemptSpacing)
 		}
 	}
-	return checks
+	if len(details) == 0 {
+		details = nil
+	}
+	return checks, details
 }
 
 // doRetries executes retries and returns last error.
-func (c HTTPChecker) doRetries() error {
+func (c HTTPChecker) doRetries(ctx context.Context, details map[string]interface{}) error {
 	j := 1
 	for {
 		if c.RetrySpacing > 0 {
-			time.Sleep(c.RetrySpacing)
+			sleep(ctx, c.RetrySpacing)
 		}
-		err := c.doCheck()
-		if j >= c.Retries || err == nil {
+		err := c.doCheck(ctx, details)
+		if j >= c.Retries || err == nil || ctx.Err() != nil {
 			return err
 		}
 		j++
 	}
 }
 
-// doCheck executes check and returns error.
-func (c HTTPChecker) doCheck() error {
+// doCheck executes check and returns error. The details
+// of the response are recorded in details.
+func (c HTTPChecker) doCheck(ctx context.Context, details map[string]interface{}) error {
 	// recreate http request to run dns resolution for each iteration
 	req, err := http.NewRequest("GET", c.URL, nil)
 	if err != nil {
 		return err
 	}
+	req = req.WithContext(ctx)
 	if c.Headers != nil {
 		for key, header := range c.Headers {
 			evalEnv, _ := envsubst.EvalEnv(strings.Join(header, ", "))
@@ -189,6 +226,16 @@ func (c HTTPChecker) doCheck() error {
 		return err
 	}
 	defer resp.Body.Close()
+	details["status_code"] = resp.StatusCode
+	if resp.ContentLength >= 0 {
+		details["content_length"] = resp.ContentLength
+	}
+	if resp.Request != nil && resp.Request.URL.String() != c.URL {
+		details["final_url"] = resp.Request.URL.String()
+	}
+	if resp.TLS != nil {
+		certificateDetails(details, "tls_", *resp.TLS)
+	}
 	err = c.checkDown(resp)
 	if err != nil {
 		return err
@@ -204,44 +251,37 @@ func (c HTTPChecker) conclude(result Result) Result {
 	result.ThresholdRTT = c.ThresholdRTT
 
 	if len(c.IgnoreTimes) > 0 && c.IgnoreDuration > 0 {
-		timeZone := os.Getenv("TZ")
-		if timeZone == "" {
-			timeZone = "Europe/Paris"
-		}
-		location, _ := time.LoadLocation(timeZone)
-		now := time.Now().UTC().In(location)
+		location, _ := c.ignoreLocation()
+		now := time.Now().In(location)
 		for i := range c.IgnoreTimes {
 			start, _ := time.ParseInLocation("15:04:05", c.IgnoreTimes[i], location)
 			start = start.AddDate(now.Year(), int(now.Month())-1, now.Day()-1)
 			start = start.In(location)
 			end := start.Add(c.IgnoreDuration)
 			if now.After(start) && now.Before(end) {
-				result.Healthy = true
-				return result
+				result.Maintenance = true
+				break
 			}
 		}
 	}
 
+	stats := result.ComputeStats()
+
 	// Check errors (down)
-	for i := range result.Times {
-		if result.Times[i].Error != "" {
-			if c.Degraded {
-				result.Degraded = true
-			} else {
-				result.Down = true
-			}
-			return result
+	if c.down(stats) {
+		if c.Degraded {
+			result.Degraded = true
+		} else {
+			result.Down = true
 		}
+		return result
 	}
 
 	// Check round trip time (degraded)
-	if c.ThresholdRTT > 0 {
-		stats := result.ComputeStats()
-		if stats.Median > c.ThresholdRTT {
-			result.Notice = fmt.Sprintf("median round trip time exceeded threshold (%s)", c.ThresholdRTT)
-			result.Degraded = true
-			return result
-		}
+	if degraded, notice := c.degraded(stats, c.ThresholdRTT); degraded {
+		result.Notice = notice
+		result.Degraded = true
+		return result
 	}
 
 	result.Healthy = true
@@ -39,6 +39,9 @@ func TestHTTPChecker(t *testing.T) {
 	if got, want := len(result.Times), hc.Attempts; got != want {
 		t.Errorf("Expected %d attempts, got %d", want, got)
 	}
+	if got, want := result.Details["status_code"], http.StatusOK; got != want {
+		t.Errorf("Expected status_code detail %v, got %v", want, got)
+	}
 	ts := time.Unix(0, result.Timestamp)
 	if time.Since(ts) > 5*time.Second {
 		t.Errorf("Expected timestamp to be recent, got %s", ts)
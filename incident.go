@@ -0,0 +1,325 @@
+package checkup
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// IncidentsDocument is the name of the document in which
+// incidents are kept in a DocumentStorage.
+const IncidentsDocument = "incidents.json"
+
+// IncidentStatus is the status of an incident.
+type IncidentStatus string
+
+// The statuses of an incident, in the order they usually
+// go through.
+const (
+	IncidentInvestigating IncidentStatus = "investigating"
+	IncidentIdentified    IncidentStatus = "identified"
+	IncidentMonitoring    IncidentStatus = "monitoring"
+	IncidentResolved      IncidentStatus = "resolved"
+)
+
+// IncidentStatuses are the valid statuses of an incident.
+var IncidentStatuses = []IncidentStatus{
+	IncidentInvestigating,
+	IncidentIdentified,
+	IncidentMonitoring,
+	IncidentResolved,
+}
+
+// Incident is a disruption of service, as posted by its
+// operators, with the updates they posted about it.
+type Incident struct {
+	// ID identifies the incident; incidents are numbered
+	// from 1 as they are opened.
+	ID     int            `json:"id"`
+	Title  string         `json:"title"`
+	Status IncidentStatus `json:"status"`
+
+	// Endpoints are the titles of the affected endpoints,
+	// if known.
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// Opened and Resolved are the times, in Unix
+	// nanoseconds, at which the incident was opened and
+	// resolved.
+	Opened   int64 `json:"opened"`
+	Resolved int64 `json:"resolved,omitempty"`
+
+	// Updates is the log of the incident, oldest first.
+	Updates []IncidentUpdate `json:"updates"`
+}
+
+// IncidentUpdate is an update posted about an incident.
+type IncidentUpdate struct {
+	Timestamp int64          `json:"timestamp"`
+	Status    IncidentStatus `json:"status"`
+	Message   string         `json:"message"`
+}
+
+// Active returns whether i is not resolved yet.
+func (i Incident) Active() bool {
+	return i.Status != IncidentResolved
+}
+
+// String returns i and its updates in a human-readable
+// format.
+func (i Incident) String() string {
+	s := fmt.Sprintf("#%d [%s] %s\n", i.ID, i.Status, i.Title)
+	if len(i.Endpoints) > 0 {
+		s += fmt.Sprintf("  Endpoints: %s\n", strings.Join(i.Endpoints, ", "))
+	}
+	s += fmt.Sprintf("     Opened: %s\n", formatNano(i.Opened))
+	if i.Resolved != 0 {
+		s += fmt.Sprintf("   Resolved: %s (after %s)\n", formatNano(i.Resolved),
+			time.Duration(i.Resolved-i.Opened).Round(time.Second))
+	}
+	for _, u := range i.Updates {
+		s += fmt.Sprintf("    %s  %s: %s\n", formatNano(u.Timestamp), u.Status, u.Message)
+	}
+	return s
+}
+
+// ReadIncidents returns the incidents in storage, newest
+// first.
+func ReadIncidents(storage DocumentStorage) ([]Incident, error) {
+	b, err := storage.FetchDocument(IncidentsDocument)
+	if os.IsNotExist(err) {
+		return []Incident{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading incidents: %v", err)
+	}
+	var incidents []Incident
+	if err := json.Unmarshal(b, &incidents); err != nil {
+		return nil, fmt.Errorf("reading incidents: %v", err)
+	}
+	sort.SliceStable(incidents, func(i, j int) bool { return incidents[i].ID > incidents[j].ID })
+	return incidents, nil
+}
+
+// Incidents returns the incidents in the storage of c,
+// newest first.
+func (c Checkup) Incidents() ([]Incident, error) {
+	storage, err := c.documents()
+	if err != nil {
+		return nil, err
+	}
+	return ReadIncidents(storage)
+}
+
+// OpenIncident opens an incident with title in the storage
+// of c, affecting endpoints, which must be the titles of
+// checkers of c if c has any. Its status is
+// IncidentInvestigating if not given, and message is its
+// first update.
+func (c Checkup) OpenIncident(title string, endpoints []string, status IncidentStatus, message string) (Incident, error) {
+	var incident Incident
+	if title == "" {
+		return incident, fmt.Errorf("an incident needs a title")
+	}
+	if message == "" {
+		return incident, fmt.Errorf("an incident needs a message")
+	}
+	if status == "" {
+		status = IncidentInvestigating
+	}
+	if err := validIncidentStatus(status); err != nil {
+		return incident, err
+	}
+	endpoints, err := c.endpointTitles(endpoints)
+	if err != nil {
+		return incident, err
+	}
+
+	err = c.changeIncidents(func(incidents []Incident) ([]Incident, error) {
+		now := Timestamp()
+		incident = Incident{
+			ID:        1,
+			Title:     title,
+			Status:    status,
+			Endpoints: endpoints,
+			Opened:    now,
+			Updates:   []IncidentUpdate{{Timestamp: now, Status: status, Message: message}},
+		}
+		if len(incidents) > 0 {
+			incident.ID = incidents[0].ID + 1
+		}
+		if status == IncidentResolved {
+			incident.Resolved = now
+		}
+		return append([]Incident{incident}, incidents...), nil
+	})
+	return incident, err
+}
+
+// UpdateIncident posts message as an update of the
+// incident id in the storage of c, changing its status to
+// status if given. Setting its status to IncidentResolved
+// resolves it. Resolved incidents cannot be updated.
+func (c Checkup) UpdateIncident(id int, status IncidentStatus, message string) (Incident, error) {
+	var incident Incident
+	if message == "" {
+		return incident, fmt.Errorf("an update needs a message")
+	}
+	if status != "" {
+		if err := validIncidentStatus(status); err != nil {
+			return incident, err
+		}
+	}
+
+	err := c.changeIncidents(func(incidents []Incident) ([]Incident, error) {
+		for i := range incidents {
+			if incidents[i].ID != id {
+				continue
+			}
+			in := &incidents[i]
+			if !in.Active() {
+				return nil, fmt.Errorf("incident #%d is already resolved", id)
+			}
+			if status != "" {
+				in.Status = status
+			}
+			now := Timestamp()
+			in.Updates = append(in.Updates, IncidentUpdate{Timestamp: now, Status: in.Status, Message: message})
+			if in.Status == IncidentResolved {
+				in.Resolved = now
+			}
+			incident = *in
+			return incidents, nil
+		}
+		return nil, fmt.Errorf("no incident #%d", id)
+	})
+	return incident, err
+}
+
+// ResolveIncident resolves the incident id in the storage
+// of c, with message as its last update.
+func (c Checkup) ResolveIncident(id int, message string) (Incident, error) {
+	return c.UpdateIncident(id, IncidentResolved, message)
+}
+
+// changeIncidents replaces the incidents in the storage of
+// c with those returned by change, unless it fails. The
+// document is locked meanwhile; see lockDocument.
+func (c Checkup) changeIncidents(change func([]Incident) ([]Incident, error)) error {
+	storage, err := c.documents()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockDocument(storage, IncidentsDocument)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	incidents, err := ReadIncidents(storage)
+	if err != nil {
+		return err
+	}
+	incidents, err = change(incidents)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(incidents)
+	if err != nil {
+		return err
+	}
+	if err := storage.StoreDocument(IncidentsDocument, b); err != nil {
+		return fmt.Errorf("storing incidents: %v", err)
+	}
+	return nil
+}
+
+// documents returns the storage of c as a DocumentStorage.
+func (c Checkup) documents() (DocumentStorage, error) {
+	if c.Storage == nil {
+		return nil, fmt.Errorf("no storage configured")
+	}
+	storage, ok := c.Storage.(DocumentStorage)
+	if !ok {
+		return nil, fmt.Errorf("storage cannot store documents")
+	}
+	return storage, nil
+}
+
+// documentsMu serializes the changes of documents within
+// the process.
+var documentsMu sync.Mutex
+
+// documentLocker is implemented by DocumentStorages that
+// can lock a document against changes from other processes,
+// such as several invocations of checkup.
+type documentLocker interface {
+	lockDocument(name string) (unlock func(), err error)
+}
+
+// lockDocument locks the document name of storage until
+// the returned function is called, so that reading and
+// changing it doesn't lose concurrent changes: within the
+// process, and across processes if storage is a
+// documentLocker, like FS.
+func lockDocument(storage DocumentStorage, name string) (func(), error) {
+	documentsMu.Lock()
+	l, ok := storage.(documentLocker)
+	if !ok {
+		return documentsMu.Unlock, nil
+	}
+	unlock, err := l.lockDocument(name)
+	if err != nil {
+		documentsMu.Unlock()
+		return nil, err
+	}
+	return func() {
+		unlock()
+		documentsMu.Unlock()
+	}, nil
+}
+
+// endpointTitles returns titles as the endpoint names of
+// the checkers of c, matched case-insensitively, or an
+// error if one matches none. Titles are returned as is if c
+// has no checkers.
+func (c Checkup) endpointTitles(titles []string) ([]string, error) {
+	if len(c.Checkers) == 0 {
+		return titles, nil
+	}
+	names := make(map[string]string)
+	for _, checker := range c.Checkers {
+		name := nameOf(checker)
+		names[strings.ToLower(name)] = name
+	}
+	var matched []string
+	for _, title := range titles {
+		name, ok := names[strings.ToLower(title)]
+		if !ok {
+			return nil, fmt.Errorf("unknown endpoint %q", title)
+		}
+		matched = append(matched, name)
+	}
+	return matched, nil
+}
+
+// validIncidentStatus returns an error if status is not
+// one of IncidentStatuses.
+func validIncidentStatus(status IncidentStatus) error {
+	var valid []string
+	for _, s := range IncidentStatuses {
+		if s == status {
+			return nil
+		}
+		valid = append(valid, string(s))
+	}
+	return fmt.Errorf("invalid incident status %q; want one of %s", status, strings.Join(valid, ", "))
+}
+
+// formatNano formats the Unix nanoseconds ts in RFC 3339
+// format, in the local time zone.
+func formatNano(ts int64) string {
+	return time.Unix(0, ts).Format(time.RFC3339)
+}
@@ -0,0 +1,122 @@
+package checkup
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestIncidents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, storage := range []Storage{FS{Dir: dir}, SQL{SqliteDBFile: filepath.Join(dir, "checkup.db")}} {
+		c := Checkup{
+			Checkers: []Checker{named{Name: "API"}, named{Name: "Web"}},
+			Storage:  storage,
+		}
+		incidents, err := c.Incidents()
+		if err != nil || len(incidents) != 0 {
+			t.Fatalf("%T: Expected no incidents, got %v (%v)", storage, incidents, err)
+		}
+
+		first, err := c.OpenIncident("Slow API", []string{"api"}, "", "Looking into it")
+		if err != nil {
+			t.Fatalf("%T: %v", storage, err)
+		}
+		if first.ID != 1 || first.Status != IncidentInvestigating || first.Endpoints[0] != "API" || len(first.Updates) != 1 {
+			t.Errorf("%T: Expected incident #1 about API to be investigated, got %+v", storage, first)
+		}
+		if _, err := c.OpenIncident("Mail down", []string{"Mail"}, "", "Looking into it"); err == nil {
+			t.Errorf("%T: Expected an error for an unknown endpoint", storage)
+		}
+		if _, err := c.OpenIncident("Web down", nil, "broken", "Looking into it"); err == nil {
+			t.Errorf("%T: Expected an error for an invalid status", storage)
+		}
+		second, err := c.OpenIncident("Web down", []string{"Web"}, IncidentIdentified, "Disk full")
+		if err != nil {
+			t.Fatalf("%T: %v", storage, err)
+		}
+		if second.ID != 2 {
+			t.Errorf("%T: Expected incident #2, got #%d", storage, second.ID)
+		}
+
+		updated, err := c.UpdateIncident(1, IncidentMonitoring, "Fixed, monitoring")
+		if err != nil {
+			t.Fatalf("%T: %v", storage, err)
+		}
+		if updated.Status != IncidentMonitoring || len(updated.Updates) != 2 || updated.Updates[1].Message != "Fixed, monitoring" {
+			t.Errorf("%T: Expected incident #1 to be monitored, got %+v", storage, updated)
+		}
+		resolved, err := c.ResolveIncident(1, "Resolved")
+		if err != nil {
+			t.Fatalf("%T: %v", storage, err)
+		}
+		if resolved.Active() || resolved.Resolved == 0 || resolved.Updates[2].Status != IncidentResolved {
+			t.Errorf("%T: Expected incident #1 to be resolved, got %+v", storage, resolved)
+		}
+		if _, err := c.UpdateIncident(1, "", "Again"); err == nil {
+			t.Errorf("%T: Expected an error updating a resolved incident", storage)
+		}
+		if _, err := c.UpdateIncident(3, "", "Nothing"); err == nil {
+			t.Errorf("%T: Expected an error updating an unknown incident", storage)
+		}
+
+		incidents, err = c.Incidents()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(incidents) != 2 || incidents[0].ID != 2 || !incidents[0].Active() || incidents[1].Active() {
+			t.Errorf("%T: Expected incidents #2 and #1, got %+v", storage, incidents)
+		}
+		if s := incidents[1].String(); !strings.HasPrefix(s, "#1 [resolved] Slow API\n  Endpoints: API\n") {
+			t.Errorf("%T: Unexpected incident:\n%s", storage, s)
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	(&Server{Storage: FS{Dir: dir}}).ServeHTTP(rec, httptest.NewRequest("GET", "/check_files/incidents.json", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"title":"Web down"`) {
+		t.Errorf("Expected the incidents to be served, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestOpenIncidentsConcurrently(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := Checkup{Checkers: []Checker{named{Name: "API"}}, Storage: FS{Dir: dir}}
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := c.OpenIncident("Slow API", nil, "", "Looking into it"); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	incidents, err := c.Incidents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(incidents) != 10 {
+		t.Errorf("Expected 10 incidents, got %d", len(incidents))
+	}
+	if _, err := os.Stat(filepath.Join(dir, IncidentsDocument+".lock")); !os.IsNotExist(err) {
+		t.Errorf("Expected the lock file to be removed, got %v", err)
+	}
+}
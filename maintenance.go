@@ -0,0 +1,423 @@
+package checkup
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MaintenanceWindow is a period of planned work during
+// which results are reported with the Maintenance status.
+// A window is either a one-off range (Start and End), a
+// weekly recurrence (Days and At) or a cron expression
+// (Schedule); recurring windows last for Duration.
+type MaintenanceWindow struct {
+	// Start and End delimit a one-off window.
+	Start time.Time `json:"start,omitempty"`
+	End   time.Time `json:"end,omitempty"`
+
+	// Days are the days of the week on which a weekly
+	// window starts, such as "sun" or "saturday". If
+	// empty while At is set, the window starts every day.
+	Days []string `json:"days,omitempty"`
+
+	// At is the time of day ("15:04") at which a weekly
+	// window starts.
+	At string `json:"at,omitempty"`
+
+	// Schedule is a cron expression for the start of a
+	// recurring window. See ParseSchedule.
+	Schedule string `json:"schedule,omitempty"`
+
+	// Duration is how long a recurring window lasts.
+	Duration time.Duration `json:"duration,omitempty"`
+
+	// Timezone is the IANA name of the time zone in which
+	// At and Schedule are evaluated. Default is the time
+	// zone of the checker the window applies to.
+	Timezone string `json:"timezone,omitempty"`
+
+	// Endpoints restricts a window configured on a Checkup
+	// to the results with these titles. If empty, the window
+	// applies to all results.
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// Message is an optional message to attach to the
+	// results during the window, to be shown on the status
+	// page. It doesn't replace a message already set.
+	Message string `json:"message,omitempty"`
+}
+
+// Active returns whether t is within w. Times of day are
+// evaluated in w.Timezone, or in loc if it isn't set.
+func (w MaintenanceWindow) Active(t time.Time, loc *time.Location) (bool, error) {
+	if w.oneOff() {
+		if err := w.checkOneOff(); err != nil {
+			return false, err
+		}
+		return !t.Before(w.Start) && t.Before(w.End), nil
+	}
+
+	schedule, err := w.schedule(loc)
+	if err != nil {
+		return false, err
+	}
+
+	// t is in the window if the window started within
+	// Duration before t
+	start := schedule.Next(t.Add(-w.Duration))
+	return !start.After(t), nil
+}
+
+// validate returns an error if w is misconfigured, so that
+// it is reported as the configuration is loaded rather than
+// as results are checked.
+func (w MaintenanceWindow) validate() error {
+	if w.oneOff() {
+		return w.checkOneOff()
+	}
+	_, err := w.schedule(time.UTC)
+	return err
+}
+
+// oneOff returns whether w is a one-off window.
+func (w MaintenanceWindow) oneOff() bool {
+	return !w.Start.IsZero() || !w.End.IsZero()
+}
+
+// checkOneOff returns an error if w, a one-off window, is
+// misconfigured.
+func (w MaintenanceWindow) checkOneOff() error {
+	if w.Start.IsZero() || w.End.IsZero() || w.At != "" || w.Schedule != "" || len(w.Days) > 0 {
+		return fmt.Errorf("maintenance window: a one-off window needs start and end, and nothing else")
+	}
+	if !w.Start.Before(w.End) {
+		return fmt.Errorf("maintenance window: a one-off window must start before it ends")
+	}
+	return nil
+}
+
+// schedule returns the schedule of the starts of w, a
+// recurring window, evaluating times of day in w.Timezone,
+// or in loc if it isn't set.
+func (w MaintenanceWindow) schedule(loc *time.Location) (Schedule, error) {
+	if w.Duration <= 0 {
+		return nil, fmt.Errorf("maintenance window: a recurring window needs a duration")
+	}
+	if w.Timezone != "" {
+		var err error
+		loc, err = time.LoadLocation(w.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("maintenance window: invalid timezone: %v", err)
+		}
+	}
+	spec, err := w.spec()
+	if err != nil {
+		return nil, err
+	}
+	schedule, err := ParseSchedule(spec, loc)
+	if err != nil {
+		return nil, fmt.Errorf("maintenance window: %v", err)
+	}
+	return schedule, nil
+}
+
+// spec returns the cron expression for the start of w.
+func (w MaintenanceWindow) spec() (string, error) {
+	switch {
+	case w.Schedule != "" && w.At != "":
+		return "", fmt.Errorf("maintenance window: both schedule and at are set")
+	case w.Schedule != "":
+		return w.Schedule, nil
+	case w.At == "":
+		return "", fmt.Errorf("maintenance window: no start, at or schedule set")
+	}
+
+	at, err := time.Parse("15:04", w.At)
+	if err != nil {
+		return "", fmt.Errorf("maintenance window: invalid time of day %q (want 15:04)", w.At)
+	}
+	days := "*"
+	if len(w.Days) > 0 {
+		names := make([]string, len(w.Days))
+		for i, day := range w.Days {
+			if len(day) < 3 {
+				return "", fmt.Errorf("maintenance window: invalid day %q", day)
+			}
+			names[i] = strings.ToLower(day[:3])
+		}
+		days = strings.Join(names, ",")
+	}
+	return fmt.Sprintf("%d %d * * %s", at.Minute(), at.Hour(), days), nil
+}
+
+// appliesTo returns whether w applies to the result titled title.
+func (w MaintenanceWindow) appliesTo(title string) bool {
+	if len(w.Endpoints) == 0 {
+		return true
+	}
+	for _, endpoint := range w.Endpoints {
+		if strings.EqualFold(endpoint, title) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyMaintenance flags result as under maintenance if any
+// of windows that apply to it is active at the time of the
+// check, evaluating times of day in loc.
+func applyMaintenance(result Result, windows []MaintenanceWindow, loc *time.Location) (Result, error) {
+	t := time.Unix(0, result.Timestamp)
+	for _, w := range windows {
+		if !w.appliesTo(result.Title) {
+			continue
+		}
+		active, err := w.Active(t, loc)
+		if err != nil {
+			return result, err
+		}
+		if active {
+			result.Maintenance = true
+			if result.Message == "" {
+				result.Message = w.Message
+			}
+		}
+	}
+	return result, nil
+}
+
+// MaintenanceDocument is the name of the document in which
+// scheduled maintenance is kept in a DocumentStorage.
+const MaintenanceDocument = "maintenance.json"
+
+// ScheduledMaintenance is a one-off maintenance window
+// announced ahead of time. It is kept in storage, where the
+// status page lists it, and applies to the results checked
+// during the window like a window of the config.
+type ScheduledMaintenance struct {
+	// ID identifies the window; windows are numbered from
+	// 1 as they are scheduled.
+	ID int `json:"id"`
+
+	// Endpoints are the titles of the endpoints under
+	// maintenance. If empty, the window applies to all
+	// endpoints.
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	Start   time.Time `json:"start"`
+	End     time.Time `json:"end"`
+	Message string    `json:"message,omitempty"`
+
+	// Scheduled is the time, in Unix nanoseconds, at which
+	// the window was scheduled.
+	Scheduled int64 `json:"scheduled"`
+}
+
+// Window returns m as a one-off maintenance window.
+func (m ScheduledMaintenance) Window() MaintenanceWindow {
+	return MaintenanceWindow{
+		Start:     m.Start,
+		End:       m.End,
+		Endpoints: m.Endpoints,
+		Message:   m.Message,
+	}
+}
+
+// Ended returns whether m is over at t.
+func (m ScheduledMaintenance) Ended(t time.Time) bool {
+	return !t.Before(m.End)
+}
+
+// String returns m in a human-readable format.
+func (m ScheduledMaintenance) String() string {
+	endpoints := "all endpoints"
+	if len(m.Endpoints) > 0 {
+		endpoints = strings.Join(m.Endpoints, ", ")
+	}
+	s := fmt.Sprintf("#%d %s - %s (%s) %s", m.ID, m.Start.Local().Format(time.RFC3339),
+		m.End.Local().Format(time.RFC3339), m.End.Sub(m.Start), endpoints)
+	if m.Message != "" {
+		s += ": " + m.Message
+	}
+	return s
+}
+
+// ReadMaintenance returns the maintenance scheduled in
+// storage, by start time.
+func ReadMaintenance(storage DocumentStorage) ([]ScheduledMaintenance, error) {
+	b, err := storage.FetchDocument(MaintenanceDocument)
+	if os.IsNotExist(err) {
+		return []ScheduledMaintenance{}, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading scheduled maintenance: %v", err)
+	}
+	var scheduled []ScheduledMaintenance
+	if err := json.Unmarshal(b, &scheduled); err != nil {
+		return nil, fmt.Errorf("reading scheduled maintenance: %v", err)
+	}
+	sort.SliceStable(scheduled, func(i, j int) bool { return scheduled[i].Start.Before(scheduled[j].Start) })
+	return scheduled, nil
+}
+
+// MaintenanceSchedule returns the maintenance scheduled in
+// the storage of c, by start time.
+func (c Checkup) MaintenanceSchedule() ([]ScheduledMaintenance, error) {
+	storage, err := c.documents()
+	if err != nil {
+		return nil, err
+	}
+	return ReadMaintenance(storage)
+}
+
+// ScheduleMaintenance schedules maintenance of endpoints,
+// which must be the titles of checkers of c if c has any,
+// from start to end, in the storage of c. If endpoints is
+// empty, the maintenance is of all endpoints. Message is
+// attached to the results during the window.
+func (c Checkup) ScheduleMaintenance(endpoints []string, start, end time.Time, message string) (ScheduledMaintenance, error) {
+	var m ScheduledMaintenance
+	if start.IsZero() || end.IsZero() {
+		return m, fmt.Errorf("scheduled maintenance needs a start and an end")
+	}
+	if !end.After(start) {
+		return m, fmt.Errorf("scheduled maintenance must end after it starts")
+	}
+	if end.Before(time.Now()) {
+		return m, fmt.Errorf("scheduled maintenance must end in the future")
+	}
+	endpoints, err := c.endpointTitles(endpoints)
+	if err != nil {
+		return m, err
+	}
+
+	err = c.changeMaintenance(func(scheduled []ScheduledMaintenance) ([]ScheduledMaintenance, error) {
+		m = ScheduledMaintenance{
+			ID:        1,
+			Endpoints: endpoints,
+			Start:     start,
+			End:       end,
+			Message:   message,
+			Scheduled: Timestamp(),
+		}
+		for _, s := range scheduled {
+			if s.ID >= m.ID {
+				m.ID = s.ID + 1
+			}
+		}
+		return append(scheduled, m), nil
+	})
+	return m, err
+}
+
+// CancelMaintenance removes the maintenance id from the
+// storage of c.
+func (c Checkup) CancelMaintenance(id int) error {
+	return c.changeMaintenance(func(scheduled []ScheduledMaintenance) ([]ScheduledMaintenance, error) {
+		for i, s := range scheduled {
+			if s.ID == id {
+				return append(scheduled[:i], scheduled[i+1:]...), nil
+			}
+		}
+		return nil, fmt.Errorf("no scheduled maintenance #%d", id)
+	})
+}
+
+// changeMaintenance replaces the maintenance scheduled in
+// the storage of c with that returned by change, unless it
+// fails, dropping the windows that have ended. The document
+// is locked meanwhile, like incidents.
+func (c Checkup) changeMaintenance(change func([]ScheduledMaintenance) ([]ScheduledMaintenance, error)) error {
+	storage, err := c.documents()
+	if err != nil {
+		return err
+	}
+	unlock, err := lockDocument(storage, MaintenanceDocument)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	scheduled, err := ReadMaintenance(storage)
+	if err != nil {
+		return err
+	}
+	scheduled, err = change(scheduled)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	kept := []ScheduledMaintenance{}
+	for _, m := range scheduled {
+		if !m.Ended(now) {
+			kept = append(kept, m)
+		}
+	}
+	b, err := json.Marshal(kept)
+	if err != nil {
+		return err
+	}
+	if err := storage.StoreDocument(MaintenanceDocument, b); err != nil {
+		return fmt.Errorf("storing scheduled maintenance: %v", err)
+	}
+	return nil
+}
+
+// scheduledWindows returns the windows of the maintenance
+// scheduled in the storage of c that haven't ended at now.
+// It returns none if the storage cannot store documents,
+// and logs the error if they cannot be read, so that checks
+// carry on without them.
+func (c Checkup) scheduledWindows(now time.Time) []MaintenanceWindow {
+	storage, ok := c.Storage.(DocumentStorage)
+	if !ok {
+		return nil
+	}
+	scheduled, err := ReadMaintenance(storage)
+	if err != nil {
+		log.Println(err)
+		return nil
+	}
+	var windows []MaintenanceWindow
+	for _, m := range scheduled {
+		if !m.Ended(now) {
+			windows = append(windows, m.Window())
+		}
+	}
+	return windows
+}
+
+// maintenanceRefresh is how long the scheduled maintenance
+// read by a maintenanceCache is used before it is read again.
+const maintenanceRefresh = time.Minute
+
+// maintenanceCache keeps the windows of the maintenance
+// scheduled in storage for maintenanceRefresh, so that
+// scheduled runs don't each read them.
+type maintenanceCache struct {
+	mu      sync.Mutex
+	windows []MaintenanceWindow
+	read    time.Time
+}
+
+// scheduledWindows returns the windows of the maintenance
+// scheduled in the storage of c, like c.scheduledWindows,
+// reading them again if they were read more than
+// maintenanceRefresh ago. If m is nil, they are read every
+// time.
+func (m *maintenanceCache) scheduledWindows(c Checkup) []MaintenanceWindow {
+	now := time.Now()
+	if m == nil {
+		return c.scheduledWindows(now)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.read.IsZero() || now.Sub(m.read) >= maintenanceRefresh {
+		m.windows, m.read = c.scheduledWindows(now), now
+	}
+	return m.windows
+}
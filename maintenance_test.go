@@ -0,0 +1,230 @@
+package checkup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaintenanceWindowActive(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	// Sunday 2019-10-06, 01:30 UTC is 03:30 in Paris
+	at := time.Date(2019, 10, 6, 1, 30, 0, 0, time.UTC)
+
+	for i, test := range []struct {
+		window MaintenanceWindow
+		loc    *time.Location
+		active bool
+	}{
+		{MaintenanceWindow{Start: at.Add(-time.Hour), End: at.Add(time.Hour)}, nil, true},
+		{MaintenanceWindow{Start: at.Add(time.Minute), End: at.Add(time.Hour)}, nil, false},
+		{MaintenanceWindow{Start: at.Add(-time.Hour), End: at}, nil, false},
+		{MaintenanceWindow{Days: []string{"sunday"}, At: "03:00", Duration: time.Hour}, paris, true},
+		{MaintenanceWindow{Days: []string{"Sun"}, At: "03:00", Duration: time.Hour}, time.UTC, false},
+		{MaintenanceWindow{Days: []string{"sun"}, At: "03:00", Duration: time.Hour, Timezone: "Europe/Paris"}, time.UTC, true},
+		{MaintenanceWindow{Days: []string{"sat", "mon"}, At: "03:00", Duration: time.Hour}, paris, false},
+		{MaintenanceWindow{At: "03:15", Duration: 30 * time.Minute}, paris, true},
+		{MaintenanceWindow{At: "03:15", Duration: 10 * time.Minute}, paris, false},
+		{MaintenanceWindow{Schedule: "0 1 * * 0", Duration: time.Hour}, time.UTC, true},
+		{MaintenanceWindow{Schedule: "0 1 * * 1-6", Duration: time.Hour}, time.UTC, false},
+	} {
+		active, err := test.window.Active(at, test.loc)
+		if err != nil {
+			t.Errorf("Test %d: Didn't expect an error: %v", i, err)
+			continue
+		}
+		if active != test.active {
+			t.Errorf("Test %d: Expected Active=%v, got %v", i, test.active, active)
+		}
+	}
+
+	for i, window := range []MaintenanceWindow{
+		{Start: at},
+		{Start: at, End: at.Add(time.Hour), At: "03:00"},
+		{At: "03:00"},
+		{At: "3am", Duration: time.Hour},
+		{At: "03:00", Schedule: "@daily", Duration: time.Hour},
+		{Days: []string{"su"}, At: "03:00", Duration: time.Hour},
+		{Duration: time.Hour},
+		{Schedule: "@daily", Duration: time.Hour, Timezone: "Nowhere/Atlantis"},
+	} {
+		if _, err := window.Active(at, time.UTC); err == nil {
+			t.Errorf("Test %d: Expected an error for an invalid window, didn't get one", i)
+		}
+	}
+}
+
+func TestCheckMaintenance(t *testing.T) {
+	now := time.Now()
+	c := Checkup{
+		Checkers: []Checker{
+			named{Name: "A"},
+			named{Name: "B"},
+			named{Name: "C", CheckerOptions: CheckerOptions{
+				Maintenance: []MaintenanceWindow{{Start: now.Add(-time.Hour), End: now.Add(time.Hour)}},
+			}},
+		},
+		Maintenance: []MaintenanceWindow{
+			{Start: now.Add(-time.Hour), End: now.Add(time.Hour), Endpoints: []string{"a"}, Message: "Upgrading"},
+		},
+		Timestamp: now,
+	}
+
+	results, err := c.Check()
+	if err != nil {
+		t.Fatalf("Didn't expect an error: %v", err)
+	}
+	for i, want := range []StatusText{Maintenance, Healthy, Maintenance} {
+		if got := results[i].Status(); got != want {
+			t.Errorf("Result %d: expected status '%s', got '%s'", i, want, got)
+		}
+	}
+	if got, want := results[0].Message, "Upgrading"; got != want {
+		t.Errorf("Expected message '%s', got '%s'", want, got)
+	}
+	if !results[0].Healthy {
+		t.Error("Expected the observed conclusion to be kept during maintenance")
+	}
+}
+
+func TestJSONMaintenance(t *testing.T) {
+	for _, window := range []string{
+		`{"at":"25:99","duration":3600000000000}`,
+		`{"at":"03:00"}`,
+		`{"days":["mo"],"at":"03:00","duration":3600000000000}`,
+		`{"schedule":"not a schedule","duration":3600000000000}`,
+		`{"at":"03:00","duration":3600000000000,"timezone":"Nowhere/Else"}`,
+		`{"start":"2019-10-12T22:00:00Z"}`,
+		`{"start":"2019-10-13T02:00:00Z","end":"2019-10-12T22:00:00Z"}`,
+	} {
+		for _, config := range []string{
+			`{"maintenance":[` + window + `]}`,
+			`{"checkers":[{"type":"tcp","endpoint_name":"a","endpoint_url":"example.com:80","maintenance":[` + window + `]}]}`,
+		} {
+			var c Checkup
+			if err := json.Unmarshal([]byte(config), &c); err == nil {
+				t.Errorf("Expected an error for %s", config)
+			}
+		}
+	}
+
+	var c Checkup
+	config := `{"maintenance":[{"days":["sun"],"at":"03:00","duration":3600000000000},` +
+		`{"start":"2019-10-12T22:00:00Z","end":"2019-10-13T02:00:00Z"}]}`
+	if err := json.Unmarshal([]byte(config), &c); err != nil {
+		t.Errorf("Expected valid maintenance windows, got %v", err)
+	}
+}
+
+func TestScheduledMaintenance(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Now()
+	c := Checkup{
+		Checkers: []Checker{named{Name: "API"}, named{Name: "Web"}},
+		Storage:  FS{Dir: dir},
+	}
+	if _, err := c.ScheduleMaintenance([]string{"Mail"}, now, now.Add(time.Hour), ""); err == nil {
+		t.Error("Expected an error for an unknown endpoint")
+	}
+	if _, err := c.ScheduleMaintenance(nil, now, now.Add(-time.Hour), ""); err == nil {
+		t.Error("Expected an error for a window ending before it starts")
+	}
+	if _, err := c.ScheduleMaintenance(nil, now.Add(-2*time.Hour), now.Add(-time.Hour), ""); err == nil {
+		t.Error("Expected an error for a window in the past")
+	}
+
+	later, err := c.ScheduleMaintenance(nil, now.Add(24*time.Hour), now.Add(25*time.Hour), "Network upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	current, err := c.ScheduleMaintenance([]string{"api"}, now.Add(-time.Minute), now.Add(time.Hour), "Database upgrade")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if later.ID != 1 || current.ID != 2 || current.Endpoints[0] != "API" {
+		t.Errorf("Expected maintenance #1 and #2 of API, got %+v and %+v", later, current)
+	}
+
+	scheduled, err := c.MaintenanceSchedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(scheduled) != 2 || scheduled[0].ID != 2 || scheduled[1].ID != 1 {
+		t.Errorf("Expected maintenance #2 then #1, got %+v", scheduled)
+	}
+
+	results, err := c.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Status() != Maintenance || results[0].Message != "Database upgrade" {
+		t.Errorf("Expected API to be under maintenance, got %+v", results[0])
+	}
+	if results[1].Status() != Healthy {
+		t.Errorf("Expected Web to be healthy, got %+v", results[1])
+	}
+
+	rec := httptest.NewRecorder()
+	(&Server{Storage: FS{Dir: dir}}).ServeHTTP(rec, httptest.NewRequest("GET", "/api/maintenance", nil))
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"message":"Network upgrade"`) {
+		t.Errorf("Expected the scheduled maintenance to be served, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if err := c.CancelMaintenance(2); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CancelMaintenance(2); err == nil {
+		t.Error("Expected an error canceling unknown maintenance")
+	}
+	results, err = c.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Status() != Healthy {
+		t.Errorf("Expected API to be healthy once maintenance is canceled, got %+v", results[0])
+	}
+
+	// ended windows are dropped as maintenance is changed
+	ended := fmt.Sprintf(`[{"id":3,"start":%q,"end":%q}]`,
+		now.Add(-2*time.Hour).Format(time.RFC3339), now.Add(-time.Hour).Format(time.RFC3339))
+	if err := (FS{Dir: dir}).StoreDocument(MaintenanceDocument, []byte(ended)); err != nil {
+		t.Fatal(err)
+	}
+	current, err = c.ScheduleMaintenance(nil, now.Add(-time.Minute), now.Add(time.Hour), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	scheduled, err = c.MaintenanceSchedule()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.ID != 4 || len(scheduled) != 1 || scheduled[0].ID != 4 {
+		t.Errorf("Expected only maintenance #4 to be kept, got %+v", scheduled)
+	}
+
+	// scheduled runs read the maintenance once in a while
+	c.maintenance = &maintenanceCache{}
+	if results, err = c.Check(); err != nil || results[1].Status() != Maintenance {
+		t.Fatalf("Expected Web to be under maintenance, got %+v (%v)", results[1], err)
+	}
+	if err := c.CancelMaintenance(4); err != nil {
+		t.Fatal(err)
+	}
+	if results, err = c.Check(); err != nil || results[1].Status() != Maintenance {
+		t.Errorf("Expected the maintenance read before to still apply, got %+v (%v)", results[1], err)
+	}
+}
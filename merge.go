@@ -0,0 +1,168 @@
+package checkup
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxMergeFiles is how many of the latest check files are
+// read, at most, from each storage to merge results.
+const maxMergeFiles = 100
+
+// MergeLatest reads the latest results of each location
+// from readers, which are the storages of one or more
+// nodes, and merges them with MergeResults. Results older
+// than since are ignored, unless since is zero. Results
+// that were already merged are ignored as well.
+func MergeLatest(readers []StorageReader, quorum int, since time.Time) ([]Result, error) {
+	var results []Result
+	seen := make(map[string]bool)
+	for _, reader := range readers {
+		names, err := latestCheckFiles(reader, maxMergeFiles)
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			fetched, err := reader.Fetch(name)
+			if err != nil {
+				return nil, fmt.Errorf("fetching %s: %v", name, err)
+			}
+			for _, result := range fetched {
+				if result.Locations != nil {
+					continue
+				}
+				if !since.IsZero() && result.Timestamp < since.UnixNano() {
+					continue
+				}
+				// only the latest result of each location counts
+				key := strings.ToLower(result.Title) + "\x00" + result.location()
+				if seen[key] {
+					continue
+				}
+				seen[key] = true
+				results = append(results, result)
+			}
+		}
+	}
+	return MergeResults(results, quorum), nil
+}
+
+// MergeResults merges results of the same endpoints from
+// different locations into a single result per endpoint,
+// whose conclusion is the verdict of a quorum of locations:
+// the endpoint is down if at least quorum locations found
+// it down, degraded if at least quorum locations found it
+// degraded or down, and healthy otherwise. The merged
+// result is in maintenance if any location is. If quorum
+// is not set (<= 0), it is the majority of the locations
+// that reported the endpoint; it is never more than the
+// number of these locations.
+//
+// Results are expected to hold the latest result of each
+// location, as returned by MergeLatest. Endpoints appear
+// in the order of their first result.
+func MergeResults(results []Result, quorum int) []Result {
+	var order []string
+	byEndpoint := make(map[string][]Result)
+	for _, result := range results {
+		key := strings.ToLower(result.Title)
+		if _, ok := byEndpoint[key]; !ok {
+			order = append(order, key)
+		}
+		byEndpoint[key] = append(byEndpoint[key], result)
+	}
+
+	merged := make([]Result, 0, len(order))
+	for _, key := range order {
+		merged = append(merged, mergeEndpoint(byEndpoint[key], quorum))
+	}
+	return merged
+}
+
+// mergeEndpoint merges the results of one endpoint from
+// different locations. See MergeResults.
+func mergeEndpoint(results []Result, quorum int) Result {
+	n := len(results)
+	if quorum <= 0 {
+		quorum = n/2 + 1
+	}
+	if quorum > n {
+		quorum = n
+	}
+
+	latest := results[0]
+	for _, result := range results[1:] {
+		if result.Timestamp > latest.Timestamp {
+			latest = result
+		}
+	}
+	merged := Result{
+		Title:        latest.Title,
+		Endpoint:     latest.Endpoint,
+		Type:         latest.Type,
+		Tags:         latest.Tags,
+		Timestamp:    latest.Timestamp,
+		ThresholdRTT: latest.ThresholdRTT,
+		Details:      latest.Details,
+		Locations:    make(map[string]StatusText, n),
+	}
+
+	var down, failing int
+	for _, result := range results {
+		merged.Times = append(merged.Times, result.Times...)
+		merged.Locations[result.location()] = result.Status()
+		if result.Down {
+			down++
+		}
+		if result.Down || result.Degraded {
+			failing++
+		}
+		if result.Maintenance {
+			merged.Maintenance = true
+		}
+		if merged.Message == "" {
+			merged.Message = result.Message
+		}
+	}
+
+	switch {
+	case down >= quorum:
+		merged.Down = true
+		merged.Notice = fmt.Sprintf("down in %d of %d locations", down, n)
+	case failing >= quorum:
+		merged.Degraded = true
+		merged.Notice = fmt.Sprintf("degraded in %d of %d locations", failing, n)
+	default:
+		merged.Healthy = true
+		if failing > 0 {
+			merged.Notice = fmt.Sprintf("failing in %d of %d locations, below quorum of %d", failing, n, quorum)
+		}
+	}
+	return merged
+}
+
+// latestCheckFiles returns the names of the latest check
+// files in reader, newest first, up to limit.
+func latestCheckFiles(reader StorageReader, limit int) ([]string, error) {
+	index, err := reader.GetIndex()
+	if err != nil {
+		return nil, fmt.Errorf("reading index: %v", err)
+	}
+	return newestCheckFiles(index, limit), nil
+}
+
+// newestCheckFiles returns the names of the newest check
+// files in index, newest first, up to limit.
+func newestCheckFiles(index map[string]int64, limit int) []string {
+	names := make([]string, 0, len(index))
+	for name := range index {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool { return index[names[i]] > index[names[j]] })
+	if len(names) > limit {
+		names = names[:limit]
+	}
+	return names
+}
@@ -0,0 +1,112 @@
+package checkup
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestMergeResults(t *testing.T) {
+	result := func(location string, status StatusText) Result {
+		return Result{
+			Title:     "Example",
+			Endpoint:  "https://example.com",
+			Location:  location,
+			Timestamp: 1,
+			Times:     Attempts{{RTT: time.Second}},
+			Healthy:   status == Healthy,
+			Degraded:  status == Degraded,
+			Down:      status == Down,
+		}
+	}
+
+	for i, test := range []struct {
+		statuses []StatusText
+		quorum   int
+		want     StatusText
+	}{
+		{[]StatusText{Down, Healthy, Healthy}, 2, Healthy},
+		{[]StatusText{Down, Down, Healthy}, 2, Down},
+		{[]StatusText{Down, Degraded, Healthy}, 2, Degraded},
+		{[]StatusText{Down, Healthy, Healthy}, 1, Down},
+		{[]StatusText{Down, Down, Healthy}, 0, Down},
+		{[]StatusText{Down, Healthy, Healthy}, 0, Healthy},
+		{[]StatusText{Down}, 2, Down},
+	} {
+		var results []Result
+		for j, status := range test.statuses {
+			results = append(results, result(string(rune('a'+j)), status))
+		}
+		merged := MergeResults(results, test.quorum)
+		if len(merged) != 1 {
+			t.Fatalf("Test %d: Expected 1 merged result, got %d", i, len(merged))
+		}
+		if got := merged[0].Status(); got != test.want {
+			t.Errorf("Test %d: Expected status %s, got %s", i, test.want, got)
+		}
+		if got, want := len(merged[0].Locations), len(test.statuses); got != want {
+			t.Errorf("Test %d: Expected %d locations, got %d", i, want, got)
+		}
+		if got, want := len(merged[0].Times), len(test.statuses); got != want {
+			t.Errorf("Test %d: Expected the times of all locations (%d), got %d", i, want, got)
+		}
+	}
+}
+
+func TestMergeLatest(t *testing.T) {
+	var readers []StorageReader
+	for _, location := range []string{"paris", "london", "nyc"} {
+		dir, err := ioutil.TempDir("", "checkup")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		storage := FS{Dir: dir}
+		readers = append(readers, storage)
+
+		// an old failure, then the latest result
+		c := Checkup{
+			Checkers: []Checker{named{Name: "Example", Down: true}},
+			Location: location,
+			Storage:  storage,
+		}
+		if err := c.CheckAndStore(); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+		c.Checkers[0] = named{Name: "Example", Down: location == "paris"}
+		if err := c.CheckAndStore(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	results, err := MergeLatest(readers, 2, time.Time{})
+	if err != nil {
+		t.Fatalf("Didn't expect an error: %v", err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Expected 1 merged result, got %d", len(results))
+	}
+	if got, want := results[0].Status(), Healthy; got != want {
+		t.Errorf("Expected status %s, got %s", want, got)
+	}
+	if got, want := results[0].Locations["paris"], Down; got != want {
+		t.Errorf("Expected paris to be %s, got %s", want, got)
+	}
+	if got, want := results[0].Locations["london"], Healthy; got != want {
+		t.Errorf("Expected london to be %s, got %s", want, got)
+	}
+
+	// merged results are not merged again
+	if err := readers[0].(FS).Store(results); err != nil {
+		t.Fatal(err)
+	}
+	results, err = MergeLatest(readers[:1], 0, time.Time{})
+	if err != nil {
+		t.Fatalf("Didn't expect an error: %v", err)
+	}
+	if got, want := results[0].Status(), Down; got != want {
+		t.Errorf("Expected status %s, got %s", want, got)
+	}
+}
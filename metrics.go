@@ -0,0 +1,195 @@
+package checkup
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// endpointLabels are the labels of the metrics of endpoints.
+var endpointLabels = []string{"title", "endpoint", "type", "node", "location"}
+
+var (
+	upDesc = prometheus.NewDesc("checkup_endpoint_up",
+		"Whether the endpoint was healthy at the latest check.", endpointLabels, nil)
+	degradedDesc = prometheus.NewDesc("checkup_endpoint_degraded",
+		"Whether the endpoint was degraded at the latest check.", endpointLabels, nil)
+	downDesc = prometheus.NewDesc("checkup_endpoint_down",
+		"Whether the endpoint was down at the latest check.", endpointLabels, nil)
+	maintenanceDesc = prometheus.NewDesc("checkup_endpoint_maintenance",
+		"Whether the endpoint was under maintenance at the latest check.", endpointLabels, nil)
+	rttDesc = prometheus.NewDesc("checkup_endpoint_rtt_seconds",
+		"Round trip time statistics of the latest check.", append(endpointLabels, "stat"), nil)
+	attemptsDesc = prometheus.NewDesc("checkup_endpoint_attempts",
+		"Number of attempts of the latest check.", endpointLabels, nil)
+	attemptErrorsDesc = prometheus.NewDesc("checkup_endpoint_attempt_errors",
+		"Number of failed attempts of the latest check.", endpointLabels, nil)
+	lastCheckDesc = prometheus.NewDesc("checkup_endpoint_last_check_timestamp_seconds",
+		"Time of the latest check.", endpointLabels, nil)
+	certExpiryDesc = prometheus.NewDesc("checkup_tls_cert_expiry_timestamp_seconds",
+		"Expiry time of the certificate presented at the latest check.", endpointLabels, nil)
+	backupAgeDesc = prometheus.NewDesc("checkup_backup_age_seconds",
+		"Age of the latest backup found at the latest check.", endpointLabels, nil)
+)
+
+// Metrics collects the latest result of each endpoint and
+// the activity of checkup, for Prometheus. Set it on a
+// Checkup to record its runs, and on a Server to serve it.
+type Metrics struct {
+	mu     sync.Mutex
+	latest map[string]Result
+
+	registry       *prometheus.Registry
+	runDuration    prometheus.Histogram
+	storageErrors  prometheus.Counter
+	notifierErrors prometheus.Counter
+}
+
+// NewMetrics returns new Metrics, which include the Go
+// runtime and process metrics.
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		latest:   make(map[string]Result),
+		registry: prometheus.NewRegistry(),
+		runDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "checkup_run_duration_seconds",
+			Help:    "Duration of the runs of all checkers.",
+			Buckets: prometheus.ExponentialBuckets(0.1, 2, 10),
+		}),
+		storageErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "checkup_storage_errors_total",
+			Help: "Number of errors storing results or maintaining the storage.",
+		}),
+		notifierErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "checkup_notifier_errors_total",
+			Help: "Number of errors notifying of results.",
+		}),
+	}
+	m.registry.MustRegister(
+		m,
+		m.runDuration,
+		m.storageErrors,
+		m.notifierErrors,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+	return m
+}
+
+// Handler returns an http.Handler that serves the metrics.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// Observe records results as the latest results of their
+// endpoints, by location (or node) like the Server.
+func (m *Metrics) Observe(results []Result) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, result := range results {
+		key := strings.ToLower(result.Title) + "\x00" + result.location()
+		if latest, ok := m.latest[key]; ok && latest.Timestamp > result.Timestamp {
+			continue
+		}
+		m.latest[key] = result
+	}
+}
+
+// observeRun records a run of all checkers that took d.
+func (m *Metrics) observeRun(d time.Duration) {
+	if m != nil {
+		m.runDuration.Observe(d.Seconds())
+	}
+}
+
+// storageError records an error of the storage.
+func (m *Metrics) storageError() {
+	if m != nil {
+		m.storageErrors.Inc()
+	}
+}
+
+// notifierError records an error of the notifier.
+func (m *Metrics) notifierError() {
+	if m != nil {
+		m.notifierErrors.Inc()
+	}
+}
+
+// Describe implements prometheus.Collector.
+func (m *Metrics) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{
+		upDesc, degradedDesc, downDesc, maintenanceDesc, rttDesc,
+		attemptsDesc, attemptErrorsDesc, lastCheckDesc, certExpiryDesc, backupAgeDesc,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect implements prometheus.Collector.
+func (m *Metrics) Collect(ch chan<- prometheus.Metric) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, r := range m.latest {
+		labels := []string{r.Title, r.Endpoint, r.Type, r.Node, r.Location}
+		gauge := func(desc *prometheus.Desc, v float64, extra ...string) {
+			ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v, append(labels, extra...)...)
+		}
+
+		gauge(upDesc, boolValue(r.Healthy))
+		gauge(degradedDesc, boolValue(r.Degraded))
+		gauge(downDesc, boolValue(r.Down))
+		gauge(maintenanceDesc, boolValue(r.Maintenance))
+		gauge(lastCheckDesc, float64(r.Timestamp)/1e9)
+
+		stats := r.ComputeStats()
+		gauge(attemptsDesc, float64(len(r.Times)))
+		gauge(attemptErrorsDesc, float64(stats.Failures))
+		for stat, d := range map[string]time.Duration{
+			"min":    stats.Min,
+			"max":    stats.Max,
+			"mean":   stats.Mean,
+			"median": stats.Median,
+			"p90":    stats.P90,
+			"p95":    stats.P95,
+			"p99":    stats.P99,
+			"stddev": stats.StdDev,
+		} {
+			gauge(rttDesc, d.Seconds(), stat)
+		}
+
+		if t, ok := detailTime(r, "not_after", "tls_not_after"); ok {
+			gauge(certExpiryDesc, float64(t.Unix()))
+		}
+		if t, ok := detailTime(r, "last_modified", "created"); ok && strings.HasPrefix(r.Type, "backup:") {
+			gauge(backupAgeDesc, time.Since(t).Seconds())
+		}
+	}
+}
+
+// detailTime returns the first time found in the details of
+// r under keys.
+func detailTime(r Result, keys ...string) (time.Time, bool) {
+	for _, key := range keys {
+		if s, ok := r.Details[key].(string); ok {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return t, true
+			}
+		}
+	}
+	return time.Time{}, false
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,136 @@
+package checkup
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func scrape(t *testing.T, m *Metrics) string {
+	rec := httptest.NewRecorder()
+	m.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, err := ioutil.ReadAll(rec.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics()
+	now := time.Now().UTC()
+	expiry := now.Add(30 * 24 * time.Hour).Truncate(time.Second)
+	m.Observe([]Result{
+		{Title: "Web", Endpoint: "https://example.com", Type: "http", Node: "paris", Timestamp: now.UnixNano(),
+			Healthy: true, Times: Attempts{{RTT: 100 * time.Millisecond}, {RTT: 200 * time.Millisecond, Error: "timeout"}},
+			Details: map[string]interface{}{"tls_not_after": expiry.Format(time.RFC3339)}},
+		{Title: "Backup", Endpoint: "s3://bucket/key", Type: "backup:s3", Timestamp: now.UnixNano(), Down: true,
+			Details: map[string]interface{}{"last_modified": now.Add(-time.Hour).Format(time.RFC3339)}},
+	})
+	// older results don't replace newer ones
+	m.Observe([]Result{{Title: "web", Type: "http", Node: "paris", Timestamp: now.Add(-time.Minute).UnixNano(), Down: true}})
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		`checkup_endpoint_up{endpoint="https://example.com",location="",node="paris",title="Web",type="http"} 1`,
+		`checkup_endpoint_down{endpoint="https://example.com",location="",node="paris",title="Web",type="http"} 0`,
+		`checkup_endpoint_down{endpoint="s3://bucket/key",location="",node="",title="Backup",type="backup:s3"} 1`,
+		`checkup_endpoint_attempts{endpoint="https://example.com",location="",node="paris",title="Web",type="http"} 2`,
+		`checkup_endpoint_attempt_errors{endpoint="https://example.com",location="",node="paris",title="Web",type="http"} 1`,
+		`checkup_endpoint_rtt_seconds{endpoint="https://example.com",location="",node="paris",stat="max",title="Web",type="http"} 0.1`,
+		`checkup_tls_cert_expiry_timestamp_seconds{endpoint="https://example.com",location="",node="paris",title="Web",type="http"} `,
+		`checkup_backup_age_seconds{endpoint="s3://bucket/key",location="",node="",title="Backup",type="backup:s3"} `,
+		`checkup_run_duration_seconds_count 0`,
+		`go_goroutines `,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+	if strings.Contains(body, `title="web"`) {
+		t.Errorf("Expected older results to be ignored, got:\n%s", body)
+	}
+
+	// results from several locations are kept apart
+	var nilMetrics *Metrics
+	nilMetrics.Observe([]Result{{Title: "API"}})
+	m.Observe([]Result{
+		{Title: "API", Location: "paris", Timestamp: now.UnixNano(), Healthy: true},
+		{Title: "API", Location: "tokyo", Timestamp: now.UnixNano(), Down: true},
+	})
+	body = scrape(t, m)
+	for _, want := range []string{
+		`checkup_endpoint_up{endpoint="",location="paris",node="",title="API",type=""} 1`,
+		`checkup_endpoint_up{endpoint="",location="tokyo",node="",title="API",type=""} 0`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestMetricsErrors(t *testing.T) {
+	m := NewMetrics()
+	c := Checkup{
+		Checkers: []Checker{&fake{}},
+		Storage:  failingStorage{},
+		Notifier: failingNotifier{},
+		Metrics:  m,
+	}
+	// results are stored even if the notifier fails
+	if err := c.CheckAndStore(); err == nil || !strings.Contains(err.Error(), "notifier failed") || !strings.Contains(err.Error(), "storage failed") {
+		t.Fatalf("Expected errors from the notifier and the storage, got %v", err)
+	}
+	c.Notifier = nil
+	if err := c.CheckAndStore(); err == nil {
+		t.Fatal("Expected an error from the storage")
+	}
+
+	body := scrape(t, m)
+	for _, want := range []string{
+		"checkup_notifier_errors_total 1",
+		"checkup_storage_errors_total 2",
+		"checkup_run_duration_seconds_count 2",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+}
+
+type failingStorage struct{}
+
+func (failingStorage) Store([]Result) error { return errors.New("storage failed") }
+
+type failingNotifier struct{}
+
+func (failingNotifier) Notify([]Result) error { return errors.New("notifier failed") }
+
+func TestServerMetrics(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	storage := FS{Dir: dir}
+	if err := storage.Store([]Result{{Title: "Web", Type: "http", Timestamp: Timestamp(), Healthy: true}}); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	(&Server{Storage: storage}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("Expected no metrics without Metrics, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	(&Server{Storage: storage, Metrics: NewMetrics()}).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if want := `checkup_endpoint_up{endpoint="",location="",node="",title="Web",type="http"} 1`; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("Expected metrics of the stored results to contain %q, got:\n%s", want, rec.Body.String())
+	}
+}
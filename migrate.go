@@ -0,0 +1,245 @@
+package checkup
+
+import (
+	"archive/tar"
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CheckFile is a check file of a storage, with its name,
+// timestamp and results.
+type CheckFile struct {
+	Name      string   `json:"name"`
+	Timestamp int64    `json:"timestamp"`
+	Results   []Result `json:"results"`
+}
+
+// ArchiveFormats are the formats in which the check files
+// of a storage can be exported and imported: "ndjson",
+// which is a check file as JSON per line, and "tar",
+// which holds the check files and an index.json file like
+// the FS storage.
+var ArchiveFormats = []string{"ndjson", "tar"}
+
+// Migrate copies the check files of from into to, keeping
+// their names and timestamps, and returns how many were
+// copied. Check files that are already in to, if it is a
+// StorageReader, are skipped, so that an interrupted
+// migration can be resumed.
+func Migrate(from StorageReader, to StorageWriter) (int, error) {
+	store, err := checkFileStorer(to)
+	if err != nil {
+		return 0, err
+	}
+	var copied int
+	err = eachCheckFile(from, func(file CheckFile) error {
+		ok, err := store(file)
+		if ok {
+			copied++
+		}
+		return err
+	})
+	return copied, err
+}
+
+// Export writes the check files of from to w, oldest
+// first, in format, which is one of ArchiveFormats.
+func Export(w io.Writer, from StorageReader, format string) error {
+	switch format {
+	case "ndjson":
+		enc := json.NewEncoder(w)
+		return eachCheckFile(from, func(file CheckFile) error {
+			return enc.Encode(file)
+		})
+	case "tar":
+		tw := tar.NewWriter(w)
+		index := make(map[string]int64)
+		err := eachCheckFile(from, func(file CheckFile) error {
+			index[file.Name] = file.Timestamp
+			return writeTarJSON(tw, file.Name, file.Timestamp, file.Results)
+		})
+		if err != nil {
+			return err
+		}
+		if err := writeTarJSON(tw, indexName, time.Now().UnixNano(), index); err != nil {
+			return err
+		}
+		return tw.Close()
+	}
+	return fmt.Errorf("unknown format %q; want one of %s", format, strings.Join(ArchiveFormats, ", "))
+}
+
+// Import stores the check files read from r, in format,
+// into to, keeping their names and timestamps, and returns
+// how many were stored. As with Migrate, check files that
+// are already in to are skipped.
+func Import(r io.Reader, to StorageWriter, format string) (int, error) {
+	store, err := checkFileStorer(to)
+	if err != nil {
+		return 0, err
+	}
+	var files []CheckFile
+	switch format {
+	case "ndjson":
+		dec := json.NewDecoder(bufio.NewReader(r))
+		for {
+			var file CheckFile
+			err := dec.Decode(&file)
+			if err == io.EOF {
+				break
+			} else if err != nil {
+				return 0, fmt.Errorf("reading check file %d: %v", len(files)+1, err)
+			}
+			files = append(files, file)
+		}
+	case "tar":
+		files, err = readTar(r)
+		if err != nil {
+			return 0, err
+		}
+	default:
+		return 0, fmt.Errorf("unknown format %q; want one of %s", format, strings.Join(ArchiveFormats, ", "))
+	}
+
+	var stored int
+	for _, file := range files {
+		ok, err := store(file)
+		if err != nil {
+			return stored, err
+		}
+		if ok {
+			stored++
+		}
+	}
+	return stored, nil
+}
+
+// eachCheckFile calls f with each check file of reader,
+// oldest first.
+func eachCheckFile(reader StorageReader, f func(CheckFile) error) error {
+	index, err := reader.GetIndex()
+	if err != nil {
+		return fmt.Errorf("reading index: %v", err)
+	}
+	names := make([]string, 0, len(index))
+	for name := range index {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if index[names[i]] != index[names[j]] {
+			return index[names[i]] < index[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		results, err := reader.Fetch(name)
+		if err != nil {
+			return fmt.Errorf("fetching %s: %v", name, err)
+		}
+		if err := f(CheckFile{Name: name, Timestamp: index[name], Results: results}); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+// checkFileStorer returns a function that stores a check
+// file into to, unless it is already in to, and reports
+// whether it did. Check files whose names aren't plain
+// file names are rejected, as they could be stored outside
+// of the directory of a FS storage.
+func checkFileStorer(to StorageWriter) (func(CheckFile) (bool, error), error) {
+	existing := make(map[string]int64)
+	if reader, ok := to.(StorageReader); ok {
+		var err error
+		existing, err = reader.GetIndex()
+		if err != nil {
+			return nil, fmt.Errorf("reading index of destination: %v", err)
+		}
+	}
+	return func(file CheckFile) (bool, error) {
+		if !validCheckFileName(file.Name) {
+			return false, fmt.Errorf("invalid check file name %q", file.Name)
+		}
+		if _, ok := existing[file.Name]; ok {
+			return false, nil
+		}
+		if err := to.StoreCheckFile(file.Name, file.Timestamp, file.Results); err != nil {
+			return false, err
+		}
+		existing[file.Name] = file.Timestamp
+		return true, nil
+	}, nil
+}
+
+// validCheckFileName returns whether name is a plain file
+// name, without directories.
+func validCheckFileName(name string) bool {
+	return name != "" && name != "." && name != ".." && !strings.ContainsAny(name, `/\`)
+}
+
+// writeTarJSON writes v as JSON to tw, in the file name
+// modified at timestamp.
+func writeTarJSON(tw *tar.Writer, name string, timestamp int64, v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	err = tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0644,
+		Size:    int64(len(b)),
+		ModTime: time.Unix(0, timestamp),
+	})
+	if err != nil {
+		return err
+	}
+	_, err = tw.Write(b)
+	return err
+}
+
+// readTar reads the check files of a tar archive written
+// by Export. Their timestamps are taken from the index,
+// or from the modification times of the check files
+// missing from the index.
+func readTar(r io.Reader) ([]CheckFile, error) {
+	tr := tar.NewReader(r)
+	var files []CheckFile
+	var index map[string]int64
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("reading archive: %v", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		name := path.Base(hdr.Name)
+		if name == indexName {
+			if err := json.NewDecoder(tr).Decode(&index); err != nil {
+				return nil, fmt.Errorf("reading %s: %v", hdr.Name, err)
+			}
+			continue
+		}
+		file := CheckFile{Name: name, Timestamp: hdr.ModTime.UnixNano()}
+		if err := json.NewDecoder(tr).Decode(&file.Results); err != nil {
+			return nil, fmt.Errorf("reading %s: %v", hdr.Name, err)
+		}
+		files = append(files, file)
+	}
+	for i := range files {
+		if ts, ok := index[files[i].Name]; ok {
+			files[i].Timestamp = ts
+		}
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].Timestamp < files[j].Timestamp })
+	return files, nil
+}
@@ -0,0 +1,120 @@
+package checkup
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMigrate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	from := FS{Dir: filepath.Join(dir, "fs")}
+	if err := os.Mkdir(from.Dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i, name := range []string{"1-check.json", "2-check.json", "3-check.json"} {
+		if err := from.StoreCheckFile(name, int64(i+1)*1e9, []Result{{Title: name, Healthy: true}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	to := SQL{SqliteDBFile: filepath.Join(dir, "checkup.db")}
+	if err := to.initialize(); err != nil {
+		t.Fatal(err)
+	}
+	if err := to.StoreCheckFile("1-check.json", 1e9, []Result{{Title: "1-check.json"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := Migrate(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 check files to be copied, got %d", n)
+	}
+	fromIndex, _ := from.GetIndex()
+	toIndex, err := to.GetIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(fromIndex, toIndex) {
+		t.Errorf("Expected the same index, got %v and %v", fromIndex, toIndex)
+	}
+	results, err := to.Fetch("3-check.json")
+	if err != nil || len(results) != 1 || results[0].Title != "3-check.json" {
+		t.Errorf("Expected the results of 3-check.json, got %v (%v)", results, err)
+	}
+
+	// names from the source can't escape the destination
+	if err := to.StoreCheckFile("../escape-check.json", 4e9, nil); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Migrate(to, FS{Dir: from.Dir}); err == nil {
+		t.Error("Expected an error for a check file name with a directory")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "escape-check.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected no check file outside of the destination, got %v", err)
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	from := FS{Dir: dir}
+	for i, name := range []string{"1-check.json", "2-check.json"} {
+		if err := from.StoreCheckFile(name, int64(i+1)*1e9+5, []Result{{Title: name, Down: true}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	fromIndex, _ := from.GetIndex()
+
+	for _, format := range ArchiveFormats {
+		var buf bytes.Buffer
+		if err := Export(&buf, from, format); err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+
+		toDir, err := ioutil.TempDir(dir, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		to := FS{Dir: toDir}
+		if err := to.StoreCheckFile("2-check.json", 2e9+5, []Result{{Title: "2-check.json"}}); err != nil {
+			t.Fatal(err)
+		}
+		n, err := Import(&buf, to, format)
+		if err != nil {
+			t.Fatalf("%s: %v", format, err)
+		}
+		if n != 1 {
+			t.Errorf("%s: Expected 1 check file to be imported, got %d", format, n)
+		}
+		toIndex, _ := to.GetIndex()
+		if !reflect.DeepEqual(fromIndex, toIndex) {
+			t.Errorf("%s: Expected the same index, got %v and %v", format, fromIndex, toIndex)
+		}
+		results, err := to.Fetch("1-check.json")
+		if err != nil || len(results) != 1 || !results[0].Down {
+			t.Errorf("%s: Expected the results of 1-check.json, got %v (%v)", format, results, err)
+		}
+	}
+
+	if err := Export(&bytes.Buffer{}, from, "zip"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+	if _, err := Import(bytes.NewBufferString(`{"name":"../index.json","results":[]}`), from, "ndjson"); err == nil {
+		t.Error("Expected an error for an invalid check file name")
+	}
+}
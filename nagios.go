@@ -0,0 +1,121 @@
+package checkup
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// Exit codes of Nagios plugins.
+const (
+	NagiosOK       = 0
+	NagiosWarning  = 1
+	NagiosCritical = 2
+	NagiosUnknown  = 3
+)
+
+// nagiosStates are the names of the Nagios exit codes.
+var nagiosStates = []string{"OK", "WARNING", "CRITICAL", "UNKNOWN"}
+
+// CheckNagios runs the checker of c whose endpoint name is
+// title (case-insensitively), as a Nagios or Icinga plugin
+// would. It returns the status line to print and the code
+// to exit with; see NagiosStatus. The notifiers of c are not
+// called, and the state of endpoints kept for the state
+// rules is neither used nor updated, so that probes don't
+// skew the counts of scheduled runs. If title is empty, c
+// must have a single checker.
+func (c Checkup) CheckNagios(title string) (string, int) {
+	var only []Checker
+	for _, checker := range c.Checkers {
+		if title == "" || strings.EqualFold(nameOf(checker), title) {
+			only = append(only, checker)
+		}
+	}
+	switch {
+	case len(only) == 0:
+		return nagiosLine(NagiosUnknown, fmt.Sprintf("no checker for %q", title), ""), NagiosUnknown
+	case len(only) > 1 && title == "":
+		return nagiosLine(NagiosUnknown, "several checkers configured; choose one", ""), NagiosUnknown
+	case len(only) > 1:
+		return nagiosLine(NagiosUnknown, fmt.Sprintf("several checkers for %q", title), ""), NagiosUnknown
+	}
+
+	c = c.WithoutNotifiers()
+	c.Checkers = only
+	c.stateless = true
+	results, err := c.Check()
+	if err != nil {
+		return nagiosLine(NagiosUnknown, err.Error(), ""), NagiosUnknown
+	}
+	return NagiosStatus(results[0])
+}
+
+// NagiosStatus returns a Nagios status line for r, with
+// performance data, and the code a Nagios plugin would exit
+// with: NagiosOK if r is healthy or in maintenance,
+// NagiosWarning if it is degraded or flapping,
+// NagiosCritical if it is down, and NagiosUnknown
+// otherwise. For example:
+//
+//	CHECKUP OK - Web is healthy | rtt_min=0.1s rtt_median=0.12s;0.4 rtt_max=0.2s threshold=0.4s
+//
+// The performance data hold the minimum, median and
+// maximum round trip times, in seconds, the threshold, and
+// the number of days until the certificate expires, if
+// known.
+func NagiosStatus(r Result) (string, int) {
+	var code int
+	status := r.Status()
+	switch status {
+	case Healthy, Maintenance:
+		code = NagiosOK
+	case Degraded, Flapping:
+		code = NagiosWarning
+	case Down:
+		code = NagiosCritical
+	default:
+		code = NagiosUnknown
+	}
+
+	text := fmt.Sprintf("%s is %s", r.Title, status)
+	if status == Maintenance {
+		text = r.Title + " is under maintenance"
+	}
+	if notice := resultNotice(r); notice != "" {
+		text += ": " + notice
+	}
+
+	stats := r.ComputeStats()
+	perfdata := []string{
+		fmt.Sprintf("rtt_min=%ss", nagiosSeconds(stats.Min)),
+		fmt.Sprintf("rtt_median=%ss", nagiosSeconds(stats.Median)),
+		fmt.Sprintf("rtt_max=%ss", nagiosSeconds(stats.Max)),
+	}
+	if r.ThresholdRTT > 0 {
+		perfdata[1] += ";" + nagiosSeconds(r.ThresholdRTT)
+		perfdata = append(perfdata, fmt.Sprintf("threshold=%ss", nagiosSeconds(r.ThresholdRTT)))
+	}
+	if t, ok := detailTime(r, "not_after", "tls_not_after"); ok {
+		days := math.Floor(time.Until(t).Hours() / 24)
+		perfdata = append(perfdata, fmt.Sprintf("cert_days_left=%d", int(days)))
+	}
+	return nagiosLine(code, text, strings.Join(perfdata, " ")), code
+}
+
+// nagiosLine returns a Nagios status line with code, text
+// and perfdata, if any.
+func nagiosLine(code int, text, perfdata string) string {
+	// "|" separates the performance data
+	text = strings.NewReplacer("|", "/", "\n", " ").Replace(text)
+	line := fmt.Sprintf("CHECKUP %s - %s", nagiosStates[code], text)
+	if perfdata != "" {
+		line += " | " + perfdata
+	}
+	return line
+}
+
+func nagiosSeconds(d time.Duration) string {
+	return fmt.Sprintf("%g", d.Seconds())
+}
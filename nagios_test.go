@@ -0,0 +1,102 @@
+package checkup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNagiosStatus(t *testing.T) {
+	expiry := time.Now().Add(30*24*time.Hour + time.Hour).UTC().Format(time.RFC3339)
+	for i, test := range []struct {
+		result Result
+		line   string
+		code   int
+	}{
+		{
+			result: Result{Title: "Web", Healthy: true, ThresholdRTT: 400 * time.Millisecond,
+				Times:   Attempts{{RTT: 100 * time.Millisecond}, {RTT: 120 * time.Millisecond}, {RTT: 200 * time.Millisecond}},
+				Details: map[string]interface{}{"tls_not_after": expiry}},
+			line: "CHECKUP OK - Web is healthy | rtt_min=0.1s rtt_median=0.12s;0.4 rtt_max=0.2s threshold=0.4s cert_days_left=30",
+			code: NagiosOK,
+		},
+		{
+			result: Result{Title: "Web", Degraded: true, Notice: "p95 > 300ms (412ms)"},
+			line:   "CHECKUP WARNING - Web is degraded: p95 > 300ms (412ms) | rtt_min=0s rtt_median=0s rtt_max=0s",
+			code:   NagiosWarning,
+		},
+		{
+			result: Result{Title: "DB", Down: true, Times: Attempts{{Error: "connection refused | reset"}}},
+			line:   "CHECKUP CRITICAL - DB is down: connection refused / reset | rtt_min=0s rtt_median=0s rtt_max=0s",
+			code:   NagiosCritical,
+		},
+		{
+			result: Result{Title: "DB", Down: true, Maintenance: true},
+			line:   "CHECKUP OK - DB is under maintenance | rtt_min=0s rtt_median=0s rtt_max=0s",
+			code:   NagiosOK,
+		},
+		{
+			result: Result{Title: "DB"},
+			line:   "CHECKUP UNKNOWN - DB is unknown | rtt_min=0s rtt_median=0s rtt_max=0s",
+			code:   NagiosUnknown,
+		},
+	} {
+		line, code := NagiosStatus(test.result)
+		if line != test.line || code != test.code {
+			t.Errorf("Test %d: Expected %q (%d), got %q (%d)", i, test.line, test.code, line, code)
+		}
+	}
+}
+
+func TestCheckNagios(t *testing.T) {
+	notifier := &recorder{}
+	c := Checkup{
+		Checkers:  []Checker{named{Name: "Web"}, named{Name: "DB", Down: true}},
+		Notifier:  notifier,
+		Notifiers: []Notifier{notifier},
+	}
+
+	line, code := c.CheckNagios("db")
+	if code != NagiosCritical || !strings.HasPrefix(line, "CHECKUP CRITICAL - DB is down") {
+		t.Errorf("Expected DB to be critical, got %q (%d)", line, code)
+	}
+	if notifier.notified != 0 {
+		t.Error("Expected the notifier not to be called")
+	}
+
+	if line, code := c.CheckNagios("Mail"); code != NagiosUnknown || !strings.Contains(line, `no checker for "Mail"`) {
+		t.Errorf("Expected an unknown checker to be unknown, got %q (%d)", line, code)
+	}
+	if _, code := c.CheckNagios(""); code != NagiosUnknown {
+		t.Errorf("Expected several checkers without a title to be unknown, got %d", code)
+	}
+	c.Checkers = c.Checkers[:1]
+	if line, code := c.CheckNagios(""); code != NagiosOK {
+		t.Errorf("Expected the single checker to be run, got %q (%d)", line, code)
+	}
+}
+
+func TestCheckNagiosLeavesState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := Checkup{
+		Checkers:   []Checker{named{Name: "DB", Down: true}},
+		StateRules: StateRules{DownAfter: 2},
+		StateFile:  filepath.Join(dir, "state.json"),
+	}
+	for i := 0; i < 2; i++ {
+		if _, code := c.CheckNagios("db"); code != NagiosCritical {
+			t.Errorf("Probe %d: expected the observed status, got %d", i, code)
+		}
+	}
+	if _, err := os.Stat(c.StateFile); !os.IsNotExist(err) {
+		t.Errorf("Expected the state file not to be written, got %v", err)
+	}
+}
@@ -0,0 +1,382 @@
+package checkup
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// NotifierState keeps the alerts that notifiers sent about
+// failing endpoints, so that they notify each change of
+// status once, even across restarts. Alerts are keyed by
+// notifier and endpoint title. Implementations must be safe
+// for concurrent use.
+type NotifierState interface {
+	// Alert returns the alert of notifier about endpoint,
+	// or nil if there is none.
+	Alert(notifier, endpoint string) (*Alert, error)
+
+	// SetAlert records alert as the alert of notifier about
+	// endpoint, or removes it if alert is nil.
+	SetAlert(notifier, endpoint string, alert *Alert) error
+}
+
+// Alert is what a notifier last sent about a failing
+// endpoint.
+type Alert struct {
+	// Status is the status that was notified.
+	Status StatusText `json:"status"`
+
+	// Since is the time, in Unix nanoseconds, of the first
+	// failing result of the outage.
+	Since int64 `json:"since"`
+
+	// Notified is the time, in Unix nanoseconds, at which
+	// the alert was last sent.
+	Notified int64 `json:"notified"`
+
+	// Escalated is whether the outage was escalated. See
+	// Escalation.
+	Escalated bool `json:"escalated,omitempty"`
+}
+
+// StatefulNotifier is a Notifier that keeps its alerts in a
+// NotifierState. Checkup calls NotifyState instead of
+// Notify, with the NotifierState it is configured with.
+type StatefulNotifier interface {
+	Notifier
+	NotifyState(results []Result, state NotifierState) error
+}
+
+// ContextNotifier is a StatefulNotifier that can be
+// canceled. Once ctx is done, NotifyContext should return
+// promptly, rather than wait to retry what it failed to
+// send. Checkup calls NotifyContext instead of NotifyState.
+type ContextNotifier interface {
+	StatefulNotifier
+	NotifyContext(ctx context.Context, results []Result, state NotifierState) error
+}
+
+// TransitionKind is the kind of change of status of an
+// endpoint that a notifier tells about.
+type TransitionKind string
+
+// The kinds of transitions.
+const (
+	// TransitionFailing is an endpoint that started
+	// failing.
+	TransitionFailing TransitionKind = "failing"
+	// TransitionWorsened is a degraded endpoint that went
+	// down.
+	TransitionWorsened TransitionKind = "worsened"
+	// TransitionReminder is an endpoint that is still
+	// failing, after NotifierOptions.RemindEvery.
+	TransitionReminder TransitionKind = "reminder"
+	// TransitionRecovered is a failing endpoint that is
+	// healthy again.
+	TransitionRecovered TransitionKind = "recovered"
+)
+
+// Transition is a change of status of an endpoint that a
+// notifier tells about.
+type Transition struct {
+	Kind   TransitionKind
+	Result Result
+
+	// Previous is the alert sent before about the endpoint,
+	// if any.
+	Previous *Alert
+
+	// Alert is the alert to record once the transition is
+	// notified, or nil if the endpoint recovered.
+	Alert *Alert
+}
+
+// Recovered returns whether t is the recovery of a failing
+// endpoint.
+func (t Transition) Recovered() bool {
+	return t.Kind == TransitionRecovered
+}
+
+// Since returns the start of the outage t is about.
+func (t Transition) Since() time.Time {
+	if t.Alert != nil {
+		return time.Unix(0, t.Alert.Since)
+	}
+	return time.Unix(0, t.Previous.Since)
+}
+
+// Duration returns how long the endpoint has been failing,
+// or was failing if it recovered, as of the result of t.
+func (t Transition) Duration() time.Duration {
+	return time.Unix(0, t.Result.Timestamp).Sub(t.Since())
+}
+
+// transitions returns the transitions among results that
+// notifier has not told about yet, according to state:
+// endpoints that started failing, went from degraded to
+// down, are due a reminder or recovered. Results under
+// maintenance, or that are unreachable due to a dependency,
+// are not worth an alert. Each transition must be recorded
+// with record once it is notified.
+func (o NotifierOptions) transitions(state NotifierState, notifier string, results []Result) ([]Transition, error) {
+	var ts []Transition
+	for _, result := range results {
+		if result.Maintenance || result.UnreachableDueTo != "" {
+			continue
+		}
+		status := result.Status()
+		if status == Unknown {
+			continue
+		}
+		previous, err := state.Alert(notifier, result.Title)
+		if err != nil {
+			return nil, err
+		}
+		now := Timestamp()
+		switch {
+		case status == Healthy && previous != nil:
+			ts = append(ts, Transition{Kind: TransitionRecovered, Result: result, Previous: previous})
+		case status == Healthy:
+		case previous == nil:
+			ts = append(ts, Transition{Kind: TransitionFailing, Result: result, Alert: &Alert{
+				Status:   status,
+				Since:    result.Timestamp,
+				Notified: now,
+			}})
+		case status == Down && previous.Status != Down:
+			alert := *previous
+			alert.Status, alert.Notified = Down, now
+			ts = append(ts, Transition{Kind: TransitionWorsened, Result: result, Previous: previous, Alert: &alert})
+		case o.RemindEvery > 0 && now-previous.Notified >= int64(o.RemindEvery):
+			alert := *previous
+			alert.Notified = now
+			ts = append(ts, Transition{Kind: TransitionReminder, Result: result, Previous: previous, Alert: &alert})
+		}
+	}
+	return ts, nil
+}
+
+// record records in state that notifier told about t.
+func (t Transition) record(state NotifierState, notifier string) error {
+	return state.SetAlert(notifier, t.Result.Title, t.Alert)
+}
+
+// notifierKey returns the key of a notifier of the given
+// kind in a NotifierState, identified by parts, such as the
+// URL it posts to. Parts are hashed as they may be secret.
+func notifierKey(kind string, parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return kind + ":" + hex.EncodeToString(sum[:8])
+}
+
+// alertKey returns the key of the alert of notifier about
+// endpoint in a map of alerts.
+func alertKey(notifier, endpoint string) string {
+	return notifier + " " + strings.ToLower(endpoint)
+}
+
+// memoryNotifierState is a NotifierState kept in memory.
+type memoryNotifierState struct {
+	mu     sync.Mutex
+	alerts map[string]Alert
+}
+
+// defaultNotifierState is the NotifierState of the
+// notifiers of a Checkup configured without one.
+var defaultNotifierState = &memoryNotifierState{alerts: make(map[string]Alert)}
+
+func (m *memoryNotifierState) Alert(notifier, endpoint string) (*Alert, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	alert, ok := m.alerts[alertKey(notifier, endpoint)]
+	if !ok {
+		return nil, nil
+	}
+	return &alert, nil
+}
+
+func (m *memoryNotifierState) SetAlert(notifier, endpoint string, alert *Alert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if alert == nil {
+		delete(m.alerts, alertKey(notifier, endpoint))
+	} else {
+		m.alerts[alertKey(notifier, endpoint)] = *alert
+	}
+	return nil
+}
+
+// FileNotifierState is a NotifierState kept in a JSON file.
+type FileNotifierState struct {
+	Path string
+}
+
+// fileNotifierStateMu serializes the access to the files of
+// all FileNotifierStates.
+var fileNotifierStateMu sync.Mutex
+
+// Alert implements NotifierState.
+func (f FileNotifierState) Alert(notifier, endpoint string) (*Alert, error) {
+	fileNotifierStateMu.Lock()
+	defer fileNotifierStateMu.Unlock()
+	alerts, err := f.read()
+	if err != nil {
+		return nil, err
+	}
+	alert, ok := alerts[alertKey(notifier, endpoint)]
+	if !ok {
+		return nil, nil
+	}
+	return &alert, nil
+}
+
+// SetAlert implements NotifierState.
+func (f FileNotifierState) SetAlert(notifier, endpoint string, alert *Alert) error {
+	fileNotifierStateMu.Lock()
+	defer fileNotifierStateMu.Unlock()
+	alerts, err := f.read()
+	if err != nil {
+		return err
+	}
+	if alert == nil {
+		delete(alerts, alertKey(notifier, endpoint))
+	} else {
+		alerts[alertKey(notifier, endpoint)] = *alert
+	}
+	b, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+
+	// the state is not lost if checkup is interrupted
+	// while writing it
+	if err := writeFileAtomic(f.Path, b, 0600); err != nil {
+		return fmt.Errorf("writing notifier state: %v", err)
+	}
+	return nil
+}
+
+// read returns the alerts in the file of f.
+func (f FileNotifierState) read() (map[string]Alert, error) {
+	alerts := make(map[string]Alert)
+	b, err := ioutil.ReadFile(f.Path)
+	if os.IsNotExist(err) {
+		return alerts, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading notifier state: %v", err)
+	}
+	if err := json.Unmarshal(b, &alerts); err != nil {
+		return nil, fmt.Errorf("reading notifier state: %v", err)
+	}
+	return alerts, nil
+}
+
+// NotifierStateDocument is the name of the document in
+// which notifiers keep their alerts in a DocumentStorage
+// that isn't a NotifierState itself.
+const NotifierStateDocument = "notifier-state.json"
+
+// documentNotifierState is a NotifierState kept in the
+// NotifierStateDocument of a DocumentStorage. The alerts
+// are read once, on first use, and the document is written
+// as they change.
+type documentNotifierState struct {
+	storage DocumentStorage
+	mu      sync.Mutex
+	alerts  map[string]Alert
+}
+
+// Alert implements NotifierState.
+func (d *documentNotifierState) Alert(notifier, endpoint string) (*Alert, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.alerts == nil {
+		alerts, err := d.read()
+		if err != nil {
+			return nil, err
+		}
+		d.alerts = alerts
+	}
+	alert, ok := d.alerts[alertKey(notifier, endpoint)]
+	if !ok {
+		return nil, nil
+	}
+	return &alert, nil
+}
+
+// SetAlert implements NotifierState. The document is read
+// again and locked meanwhile, so that the alerts set by
+// other processes are kept.
+func (d *documentNotifierState) SetAlert(notifier, endpoint string, alert *Alert) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	unlock, err := lockDocument(d.storage, NotifierStateDocument)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	alerts, err := d.read()
+	if err != nil {
+		return err
+	}
+	if alert == nil {
+		delete(alerts, alertKey(notifier, endpoint))
+	} else {
+		alerts[alertKey(notifier, endpoint)] = *alert
+	}
+	b, err := json.Marshal(alerts)
+	if err != nil {
+		return err
+	}
+	if err := d.storage.StoreDocument(NotifierStateDocument, b); err != nil {
+		return fmt.Errorf("storing notifier state: %v", err)
+	}
+	d.alerts = alerts
+	return nil
+}
+
+// read returns the alerts in the document of d.
+func (d *documentNotifierState) read() (map[string]Alert, error) {
+	alerts := make(map[string]Alert)
+	b, err := d.storage.FetchDocument(NotifierStateDocument)
+	if os.IsNotExist(err) {
+		return alerts, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading notifier state: %v", err)
+	}
+	if err := json.Unmarshal(b, &alerts); err != nil {
+		return nil, fmt.Errorf("reading notifier state: %v", err)
+	}
+	return alerts, nil
+}
+
+// notifierState returns the NotifierState of the notifiers
+// of c: c.NotifierState if set, else the file named by
+// c.NotifierStateFile, else c.Storage if it is a
+// NotifierState (such as SQL), else the
+// NotifierStateDocument of c.Storage if it is a
+// DocumentStorage (such as FS or GitHub), else a state kept
+// in memory, which is lost once checkup exits.
+func (c Checkup) notifierState() NotifierState {
+	switch {
+	case c.NotifierState != nil:
+		return c.NotifierState
+	case c.NotifierStateFile != "":
+		return FileNotifierState{Path: c.NotifierStateFile}
+	}
+	switch storage := c.Storage.(type) {
+	case NotifierState:
+		return storage
+	case DocumentStorage:
+		return &documentNotifierState{storage: storage}
+	}
+	return defaultNotifierState
+}
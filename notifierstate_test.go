@@ -0,0 +1,223 @@
+package checkup
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNotifierStates(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, state := range []NotifierState{
+		&memoryNotifierState{alerts: make(map[string]Alert)},
+		FileNotifierState{Path: filepath.Join(dir, "alerts.json")},
+		SQL{SqliteDBFile: filepath.Join(dir, "checkup.db")},
+		&documentNotifierState{storage: FS{Dir: dir}},
+	} {
+		alert, err := state.Alert("slack:1", "Web")
+		if err != nil || alert != nil {
+			t.Fatalf("%T: Expected no alert, got %v (%v)", state, alert, err)
+		}
+		if err := state.SetAlert("slack:1", "Web", &Alert{Status: Down, Since: 42}); err != nil {
+			t.Fatalf("%T: %v", state, err)
+		}
+		alert, err = state.Alert("slack:1", "web")
+		if err != nil || alert == nil || alert.Status != Down || alert.Since != 42 {
+			t.Errorf("%T: Expected the alert about Web, got %v (%v)", state, alert, err)
+		}
+		if alert, _ := state.Alert("slack:2", "Web"); alert != nil {
+			t.Errorf("%T: Expected alerts to be kept by notifier, got %v", state, alert)
+		}
+		if err := state.SetAlert("slack:1", "Web", nil); err != nil {
+			t.Fatalf("%T: %v", state, err)
+		}
+		if alert, _ := state.Alert("slack:1", "Web"); alert != nil {
+			t.Errorf("%T: Expected the alert to be removed, got %v", state, alert)
+		}
+	}
+
+	// the connection to the database is kept for the next alerts
+	alertsDBsMu.Lock()
+	_, ok := alertsDBs["sqlite3\x00"+filepath.Join(dir, "checkup.db")]
+	alertsDBsMu.Unlock()
+	if !ok {
+		t.Error("Expected the connection to the database of the alerts to be kept")
+	}
+
+	// storages that keep documents keep the alerts too
+	c := Checkup{Storage: FS{Dir: dir}}
+	if err := c.notifierState().SetAlert("slack:1", "Web", &Alert{Status: Down}); err != nil {
+		t.Fatal(err)
+	}
+	alert, err := Checkup{Storage: FS{Dir: dir}}.notifierState().Alert("slack:1", "Web")
+	if err != nil || alert == nil || alert.Status != Down {
+		t.Errorf("Expected the alert to be kept in storage, got %v (%v)", alert, err)
+	}
+}
+
+func TestTransitions(t *testing.T) {
+	state := &memoryNotifierState{alerts: make(map[string]Alert)}
+	opts := NotifierOptions{RemindEvery: time.Hour}
+	start := time.Now().Add(-time.Hour).UnixNano()
+	for i, test := range []struct {
+		result Result
+		want   TransitionKind
+	}{
+		{Result{Title: "Web", Timestamp: start, Healthy: true}, ""},
+		{Result{Title: "Web", Timestamp: start, Degraded: true}, TransitionFailing},
+		{Result{Title: "Web", Timestamp: start + int64(time.Minute), Degraded: true}, ""},
+		{Result{Title: "Web", Timestamp: start + int64(time.Minute), Down: true}, TransitionWorsened},
+		{Result{Title: "Web", Timestamp: start + int64(time.Minute), Down: true}, ""},
+		{Result{Title: "Web", Timestamp: start + int64(time.Minute), Down: true, Maintenance: true}, ""},
+		{Result{Title: "Web", Timestamp: start + int64(time.Hour), Healthy: true}, TransitionRecovered},
+	} {
+		ts, err := opts.transitions(state, "test", []Result{test.result})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.want == "" {
+			if len(ts) != 0 {
+				t.Errorf("Test %d: expected no transition, got %+v", i, ts)
+			}
+			continue
+		}
+		if len(ts) != 1 || ts[0].Kind != test.want {
+			t.Fatalf("Test %d: expected a transition %s, got %+v", i, test.want, ts)
+		}
+		if ts[0].Recovered() && ts[0].Duration() != time.Hour {
+			t.Errorf("Test %d: expected an outage of 1h, got %s", i, ts[0].Duration())
+		}
+		if err := ts[0].record(state, "test"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// an alert sent over RemindEvery ago is due a reminder
+	state.SetAlert("test", "API", &Alert{Status: Down, Since: start, Notified: start})
+	ts, err := opts.transitions(state, "test", []Result{{Title: "API", Timestamp: Timestamp(), Down: true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ts) != 1 || ts[0].Kind != TransitionReminder || ts[0].Alert.Since != start {
+		t.Errorf("Expected a reminder, got %+v", ts)
+	}
+}
+
+func TestEscalation(t *testing.T) {
+	state := &memoryNotifierState{alerts: make(map[string]Alert)}
+	oncall := new(notified)
+	opts := NotifierOptions{Escalation: &Escalation{After: 30 * time.Minute, Notifier: oncall}}
+	send := func(Transition) error { return nil }
+	start := time.Now().Add(-time.Hour).UnixNano()
+	for i, result := range []Result{
+		{Title: "Web", Timestamp: start, Down: true},
+		{Title: "Web", Timestamp: start + int64(10*time.Minute), Down: true},
+		{Title: "Web", Timestamp: start + int64(40*time.Minute), Degraded: true},
+		{Title: "Web", Timestamp: start + int64(45*time.Minute), Down: true},
+		{Title: "Web", Timestamp: start + int64(50*time.Minute), Degraded: true},
+		{Title: "Web", Timestamp: start + int64(55*time.Minute), Healthy: true},
+	} {
+		if err := opts.notify(context.Background(), state, "test", []Result{result}, send); err != nil {
+			t.Fatalf("Result %d: %v", i, err)
+		}
+	}
+	if len(oncall.titles) != 3 {
+		t.Errorf("Expected the outage to be escalated once down for 30m, and until it recovered, got %d results", len(oncall.titles))
+	}
+
+	// the recoveries of outages that weren't escalated are not forwarded
+	oncall.titles = nil
+	for i, result := range []Result{
+		{Title: "Web", Timestamp: Timestamp(), Healthy: true},
+		{Title: "API", Timestamp: Timestamp(), Down: true},
+		{Title: "API", Timestamp: Timestamp(), Healthy: true},
+	} {
+		if err := opts.notify(context.Background(), state, "test", []Result{result}, send); err != nil {
+			t.Fatalf("Result %d: %v", i, err)
+		}
+	}
+	if len(oncall.titles) != 0 {
+		t.Errorf("Expected nothing to be escalated, got %v", oncall.titles)
+	}
+
+	var c Checkup
+	config := `{"notifier":{"name":"slack","webhook":"https://example.com/ops","remind_every":3600000000000,` +
+		`"escalation":{"after":1800000000000,"notifier":{"name":"slack","webhook":"https://example.com/oncall"}}}}`
+	if err := json.Unmarshal([]byte(config), &c); err != nil {
+		t.Fatal(err)
+	}
+	s, ok := c.Notifier.(Slack)
+	if !ok || s.RemindEvery != time.Hour || s.Escalation == nil || s.Escalation.Notifier.(Slack).Webhook != "https://example.com/oncall" {
+		t.Fatalf("Expected a Slack notifier with reminders and escalation, got %#v", c.Notifier)
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again Checkup
+	if err := json.Unmarshal(b, &again); err != nil || !reflect.DeepEqual(again.Notifier, c.Notifier) {
+		t.Errorf("Expected the escalation to survive a round trip, got %s (%v)", b, err)
+	}
+	if err := json.Unmarshal([]byte(`{"notifier":{"name":"slack","escalation":{"after":60}}}`), &c); err == nil {
+		t.Error("Expected an error for an escalation without notifier")
+	}
+}
+
+func TestSlackNotifyState(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	var mu sync.Mutex
+	var messages []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		mu.Lock()
+		messages = append(messages, string(b))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	c := Checkup{
+		Checkers:          []Checker{named{Name: "Web", Down: true}},
+		Notifier:          Slack{Webhook: srv.URL},
+		NotifierStateFile: filepath.Join(dir, "alerts.json"),
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := c.Check(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a restarted checkup remembers the alert
+	restarted := Checkup{
+		Checkers:          []Checker{named{Name: "Web"}},
+		Notifier:          Slack{Webhook: srv.URL},
+		NotifierStateFile: c.NotifierStateFile,
+	}
+	if _, err := restarted.Check(); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(messages) != 2 {
+		t.Fatalf("Expected an alert and a recovery, got %d messages: %v", len(messages), messages)
+	}
+	if !strings.Contains(messages[0], `"danger"`) || !strings.Contains(messages[1], `"good"`) || !strings.Contains(messages[1], "It was failing for") {
+		t.Errorf("Expected an alert and a recovery with the outage, got %v", messages)
+	}
+}
@@ -0,0 +1,394 @@
+package checkup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+// Route selects the results that are passed to a notifier.
+// A result matches a route if it matches every criterion
+// that is set.
+type Route struct {
+	// Titles are patterns, as in path.Match, of which the
+	// title of results must match one, case-insensitively,
+	// such as "*backup*".
+	Titles []string `json:"titles,omitempty"`
+
+	// Tags are the tags of which results must have one.
+	// See CheckerOptions.Tags.
+	Tags []string `json:"tags,omitempty"`
+
+	// Types are the types of checkers, such as "http" or
+	// "tls", of which results must have one.
+	Types []string `json:"types,omitempty"`
+
+	// Severities are the failures, Degraded or Down, that
+	// are notified. Flapping endpoints are degraded. Results
+	// without failure, such as healthy ones, always match,
+	// so that notifiers can tell when an endpoint recovers.
+	Severities []StatusText `json:"severities,omitempty"`
+}
+
+// Matches returns whether result matches r.
+func (r Route) Matches(result Result) bool {
+	if len(r.Titles) > 0 && !matchTitle(r.Titles, result.Title) {
+		return false
+	}
+	if len(r.Tags) > 0 && !containsFold(r.Tags, result.Tags...) {
+		return false
+	}
+	if len(r.Types) > 0 && !containsFold(r.Types, result.Type) {
+		return false
+	}
+	if severity := severityOf(result); len(r.Severities) > 0 && severity != "" {
+		for _, s := range r.Severities {
+			if s == severity {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+// validate returns an error if r is misconfigured.
+func (r Route) validate() error {
+	for _, pattern := range r.Titles {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("route: invalid title pattern %q: %v", pattern, err)
+		}
+	}
+	for _, severity := range r.Severities {
+		if severity != Degraded && severity != Down {
+			return fmt.Errorf("route: invalid severity %q; want %s or %s", severity, Degraded, Down)
+		}
+	}
+	return nil
+}
+
+// RoutedNotifier is a Notifier that passes to Notifier the
+// results that match Route only. In checkup.json, it is a
+// notifier with a "route".
+type RoutedNotifier struct {
+	Notifier Notifier
+	Route    Route
+}
+
+// Notify implements Notifier.
+func (n RoutedNotifier) Notify(results []Result) error {
+	return n.NotifyState(results, defaultNotifierState)
+}
+
+// NotifyState implements StatefulNotifier, passing state to
+// n.Notifier if it is a StatefulNotifier.
+func (n RoutedNotifier) NotifyState(results []Result, state NotifierState) error {
+	return n.NotifyContext(context.Background(), results, state)
+}
+
+// NotifyContext implements ContextNotifier, passing ctx to
+// n.Notifier if it is a ContextNotifier.
+func (n RoutedNotifier) NotifyContext(ctx context.Context, results []Result, state NotifierState) error {
+	var matched []Result
+	for _, result := range results {
+		if n.Route.Matches(result) {
+			matched = append(matched, result)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	return notifyState(ctx, n.Notifier, matched, state)
+}
+
+// NotifierOptions holds the settings that the notifiers of
+// this package support, applied to the alerts they send.
+type NotifierOptions struct {
+	// RemindEvery is how often to notify again about an
+	// endpoint that is still failing. If zero, an outage is
+	// notified once.
+	RemindEvery time.Duration `json:"remind_every,omitempty"`
+
+	// Escalation, if set, notifies another notifier about
+	// the outages that last.
+	Escalation *Escalation `json:"escalation,omitempty"`
+
+	// TitleTemplate and BodyTemplate are the text/template
+	// templates of the title and body of the messages of
+	// the notifier, executed with a Message. Defaults are
+	// DefaultTitleTemplate and DefaultBodyTemplate.
+	TitleTemplate string `json:"title_template,omitempty"`
+	BodyTemplate  string `json:"body_template,omitempty"`
+}
+
+// Escalation is a policy by which outages that last are
+// also notified to another notifier, such as the people on
+// call.
+type Escalation struct {
+	// After is how long an endpoint must have been failing,
+	// and be down, for its outage to be escalated.
+	After time.Duration `json:"after"`
+
+	// Notifier is told about escalated outages, and about
+	// their recovery.
+	Notifier Notifier `json:"notifier"`
+}
+
+// validate returns an error if o is misconfigured.
+func (o NotifierOptions) validate() error {
+	if o.RemindEvery < 0 {
+		return fmt.Errorf("invalid remind_every: %s (must be >= 0)", o.RemindEvery)
+	}
+	if err := o.validateTemplates(); err != nil {
+		return err
+	}
+	if e := o.Escalation; e != nil {
+		if e.After < 0 {
+			return fmt.Errorf("escalation: invalid after: %s (must be >= 0)", e.After)
+		}
+		if e.Notifier == nil {
+			return fmt.Errorf("escalation: no notifier configured")
+		}
+	}
+	return nil
+}
+
+// notify tells about the transitions among results,
+// according to the alerts that the notifier identified by
+// key keeps in state, calling send for each one. It then
+// escalates the outages that last, until ctx is done.
+func (o NotifierOptions) notify(ctx context.Context, state NotifierState, key string, results []Result, send func(Transition) error) error {
+	ts, err := o.transitions(state, key, results)
+	if err != nil {
+		return err
+	}
+	var errs Errors
+	for _, t := range ts {
+		if err := send(t); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := t.record(state, key); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := o.escalate(ctx, state, key, results, ts); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// notifyBatch is like notify, but calls send once with all
+// the transitions, such as to tell about them in a single
+// message.
+func (o NotifierOptions) notifyBatch(ctx context.Context, state NotifierState, key string, results []Result, send func([]Transition) error) error {
+	ts, err := o.transitions(state, key, results)
+	if err != nil {
+		return err
+	}
+	var errs Errors
+	if len(ts) > 0 {
+		if err := send(ts); err != nil {
+			errs = append(errs, err)
+		} else {
+			for _, t := range ts {
+				if err := t.record(state, key); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	if err := o.escalate(ctx, state, key, results, ts); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// escalate passes to o.Escalation.Notifier the results of
+// the endpoints whose outage, as alerted by the notifier
+// identified by key, is escalated, along with the results
+// of the escalated outages that recovered, among ts, so
+// that it can tell about their recovery.
+func (o NotifierOptions) escalate(ctx context.Context, state NotifierState, key string, results []Result, ts []Transition) error {
+	if o.Escalation == nil {
+		return nil
+	}
+	recovered := make(map[string]bool)
+	for _, t := range ts {
+		if t.Recovered() && t.Previous.Escalated {
+			recovered[strings.ToLower(t.Result.Title)] = true
+		}
+	}
+	var escalated []Result
+	for _, result := range results {
+		status := result.Status()
+		if status == Healthy {
+			if recovered[strings.ToLower(result.Title)] {
+				escalated = append(escalated, result)
+			}
+			continue
+		}
+		alert, err := state.Alert(key, result.Title)
+		if err != nil {
+			return err
+		}
+		if alert == nil {
+			continue
+		}
+		if !alert.Escalated {
+			if status != Down || result.Timestamp-alert.Since < int64(o.Escalation.After) {
+				continue
+			}
+			alert.Escalated = true
+			if err := state.SetAlert(key, result.Title, alert); err != nil {
+				return err
+			}
+		}
+		escalated = append(escalated, result)
+	}
+	if len(escalated) == 0 {
+		return nil
+	}
+	if err := notifyState(ctx, o.Escalation.Notifier, escalated, state); err != nil {
+		return fmt.Errorf("escalation: %v", err)
+	}
+	return nil
+}
+
+// notifiers returns c.Notifier, if set, and c.Notifiers.
+func (c Checkup) notifiers() []Notifier {
+	if c.Notifier == nil {
+		return c.Notifiers
+	}
+	return append([]Notifier{c.Notifier}, c.Notifiers...)
+}
+
+// WithoutNotifiers returns a copy of c without notifiers,
+// for runs whose results are notified separately with
+// Notify, if at all, such as once they are stored.
+func (c Checkup) WithoutNotifiers() Checkup {
+	c.Notifier = nil
+	c.Notifiers = nil
+	return c
+}
+
+// Notify passes results to the notifiers of c, as Check
+// does. A notifier that fails doesn't stop the others;
+// their errors are returned together.
+func (c Checkup) Notify(results []Result) error {
+	return c.notify(context.Background(), results)
+}
+
+// notify passes results to each notifier of c, until ctx is
+// done. A notifier that fails doesn't stop the others;
+// their errors are returned together.
+func (c Checkup) notify(ctx context.Context, results []Result) error {
+	var errs Errors
+	state := c.notifierState()
+	for _, notifier := range c.notifiers() {
+		if err := notifyState(ctx, notifier, results, state); err != nil {
+			c.Metrics.notifierError()
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// notifyState passes results to notifier, with state if it
+// is a StatefulNotifier, and ctx if it is a ContextNotifier.
+func notifyState(ctx context.Context, notifier Notifier, results []Result, state NotifierState) error {
+	if cn, ok := notifier.(ContextNotifier); ok {
+		return cn.NotifyContext(ctx, results, state)
+	}
+	if sn, ok := notifier.(StatefulNotifier); ok {
+		return sn.NotifyState(results, state)
+	}
+	return notifier.Notify(results)
+}
+
+// severityOf returns the failure of result that notifiers
+// alert about, Degraded or Down, or "" if there is none.
+func severityOf(result Result) StatusText {
+	switch result.Status() {
+	case Down:
+		return Down
+	case Degraded, Flapping:
+		return Degraded
+	}
+	return ""
+}
+
+// matchTitle returns whether title matches one of
+// patterns, case-insensitively.
+func matchTitle(patterns []string, title string) bool {
+	title = strings.ToLower(title)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(strings.ToLower(pattern), title); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// containsFold returns whether list contains one of values,
+// case-insensitively.
+func containsFold(list []string, values ...string) bool {
+	for _, s := range list {
+		for _, v := range values {
+			if strings.EqualFold(s, v) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MarshalJSON marshals e into JSON, with the type
+// information of its notifier.
+func (e Escalation) MarshalJSON() ([]byte, error) {
+	var nb []byte
+	if e.Notifier != nil {
+		var err error
+		nb, err = encodeNotifier(e.Notifier)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return json.Marshal(struct {
+		After    time.Duration   `json:"after"`
+		Notifier json.RawMessage `json:"notifier,omitempty"`
+	}{e.After, nb})
+}
+
+// UnmarshalJSON unmarshals b into e, with the help of the
+// type information of its notifier.
+func (e *Escalation) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		After    time.Duration   `json:"after"`
+		Notifier json.RawMessage `json:"notifier"`
+	}
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	e.After, e.Notifier = raw.After, nil
+	if raw.Notifier != nil {
+		notifier, err := decodeNotifier(raw.Notifier)
+		if err != nil {
+			return fmt.Errorf("escalation: %v", err)
+		}
+		e.Notifier = notifier
+	}
+	return nil
+}
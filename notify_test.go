@@ -0,0 +1,112 @@
+package checkup
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestRouteMatches(t *testing.T) {
+	backup := Result{Title: "Nightly Backup", Type: "s3", Down: true}
+	web := Result{Title: "Web", Type: "http", Tags: []string{"customer-facing"}, Degraded: true}
+	recovered := Result{Title: "Web", Type: "http", Tags: []string{"customer-facing"}, Healthy: true}
+	for i, test := range []struct {
+		route Route
+		want  []bool // backup, web, recovered
+	}{
+		{Route{}, []bool{true, true, true}},
+		{Route{Titles: []string{"*backup*"}}, []bool{true, false, false}},
+		{Route{Types: []string{"HTTP"}}, []bool{false, true, true}},
+		{Route{Tags: []string{"customer-facing"}}, []bool{false, true, true}},
+		{Route{Severities: []StatusText{Down}}, []bool{true, false, true}},
+		{Route{Tags: []string{"customer-facing"}, Severities: []StatusText{Down}}, []bool{false, false, true}},
+	} {
+		for j, result := range []Result{backup, web, recovered} {
+			if got := test.route.Matches(result); got != test.want[j] {
+				t.Errorf("Test %d: expected %+v to match %s: %v, got %v", i, test.route, result.Title, test.want[j], got)
+			}
+		}
+	}
+}
+
+func TestNotifiers(t *testing.T) {
+	ops, oncall := new(notified), new(notified)
+	c := Checkup{
+		Checkers: []Checker{named{Name: "Backup"}, named{Name: "Web", Down: true, CheckerOptions: CheckerOptions{Tags: []string{"web"}}}},
+		Notifier: failingNotifier{},
+		Notifiers: []Notifier{
+			RoutedNotifier{Notifier: ops, Route: Route{Titles: []string{"backup"}}},
+			RoutedNotifier{Notifier: oncall, Route: Route{Tags: []string{"web"}, Severities: []StatusText{Down}}},
+		},
+	}
+	if _, err := c.Check(); err == nil {
+		t.Error("Expected the error of the failing notifier")
+	}
+	if !reflect.DeepEqual(ops.titles, []string{"Backup"}) {
+		t.Errorf("Expected ops to be notified of Backup, got %v", ops.titles)
+	}
+	if !reflect.DeepEqual(oncall.titles, []string{"Web"}) {
+		t.Errorf("Expected on-call to be notified of Web, got %v", oncall.titles)
+	}
+
+	// results can be checked first, and notified once saved
+	results, err := c.WithoutNotifiers().Check()
+	if err != nil {
+		t.Fatalf("Expected no error without notifiers, got %v", err)
+	}
+	if len(ops.titles) != 1 || len(oncall.titles) != 1 {
+		t.Errorf("Expected no notification, got %v and %v", ops.titles, oncall.titles)
+	}
+	if err := c.Notify(results); err == nil {
+		t.Error("Expected the error of the failing notifier")
+	}
+	if len(ops.titles) != 2 || len(oncall.titles) != 2 {
+		t.Errorf("Expected the other notifiers to be notified, got %v and %v", ops.titles, oncall.titles)
+	}
+}
+
+func TestNotifiersJSON(t *testing.T) {
+	config := `{"notifiers":[{"name":"slack","webhook":"https://example.com/ops"},` +
+		`{"name":"slack","webhook":"https://example.com/oncall","route":{"tags":["customer-facing"],"severities":["down"]}}]}`
+	var c Checkup
+	if err := json.Unmarshal([]byte(config), &c); err != nil {
+		t.Fatal(err)
+	}
+	if len(c.Notifiers) != 2 {
+		t.Fatalf("Expected 2 notifiers, got %d", len(c.Notifiers))
+	}
+	routed, ok := c.Notifiers[1].(RoutedNotifier)
+	if !ok || routed.Notifier.(Slack).Webhook != "https://example.com/oncall" || routed.Route.Severities[0] != Down {
+		t.Errorf("Expected a routed Slack notifier, got %#v", c.Notifiers[1])
+	}
+
+	b, err := json.Marshal(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var again Checkup
+	if err := json.Unmarshal(b, &again); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again.Notifiers, c.Notifiers) {
+		t.Errorf("Expected notifiers to survive a round trip, got %s", b)
+	}
+
+	bad := `{"notifiers":[{"name":"slack","route":{"severities":["healthy"]}}]}`
+	if err := json.Unmarshal([]byte(bad), &c); err == nil {
+		t.Error("Expected an error for an invalid severity")
+	}
+}
+
+// notified is a Notifier that records the titles of the
+// results it is given.
+type notified struct {
+	titles []string
+}
+
+func (n *notified) Notify(results []Result) error {
+	for _, result := range results {
+		n.titles = append(n.titles, result.Title)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package checkup
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// registry maps the names used in checkup.json to
+// the types that can be configured with them.
+type registry struct {
+	kind      string
+	mu        sync.RWMutex
+	factories map[string]func() interface{}
+	names     map[reflect.Type]string
+}
+
+var (
+	checkerTypes  = newRegistry("Checker")
+	storageTypes  = newRegistry("Storage")
+	notifierTypes = newRegistry("Notifier")
+)
+
+func newRegistry(kind string) *registry {
+	return &registry{
+		kind:      kind,
+		factories: make(map[string]func() interface{}),
+		names:     make(map[reflect.Type]string),
+	}
+}
+
+// RegisterChecker makes a Checker available under name,
+// which is the "type" of a checker in checkup.json. The
+// factory must return a new, zero-valued Checker; its JSON
+// is unmarshaled into the returned value, or into a copy
+// of it if it isn't a pointer. RegisterChecker panics if
+// name is already registered, so it is meant to be called
+// from init functions.
+func RegisterChecker(name string, factory func() Checker) {
+	checkerTypes.register(name, func() interface{} { return factory() })
+}
+
+// RegisterStorage makes a Storage available under name,
+// which is the "provider" of the storage in checkup.json.
+// See RegisterChecker for the requirements on factory.
+func RegisterStorage(name string, factory func() Storage) {
+	storageTypes.register(name, func() interface{} { return factory() })
+}
+
+// RegisterNotifier makes a Notifier available under name,
+// which is the "name" of the notifier in checkup.json.
+// See RegisterChecker for the requirements on factory.
+func RegisterNotifier(name string, factory func() Notifier) {
+	notifierTypes.register(name, func() interface{} { return factory() })
+}
+
+func (r *registry) register(name string, factory func() interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if name == "" {
+		panic(fmt.Sprintf("checkup: %s registered without a name", r.kind))
+	}
+	if _, dup := r.factories[name]; dup {
+		panic(fmt.Sprintf("checkup: %s %s registered twice", r.kind, name))
+	}
+	t := reflect.TypeOf(factory())
+	if t == nil {
+		panic(fmt.Sprintf("checkup: %s %s factory returned nil", r.kind, name))
+	}
+	r.factories[name] = factory
+	r.names[t] = name
+}
+
+// name returns the name that the type of v was registered
+// with, or false if it wasn't registered.
+func (r *registry) name(v interface{}) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	name, ok := r.names[reflect.TypeOf(v)]
+	return name, ok
+}
+
+// decode unmarshals b into a new value of the type
+// registered with name.
+func (r *registry) decode(name string, b []byte) (interface{}, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown %s type", name, r.kind)
+	}
+
+	v := factory()
+	if reflect.TypeOf(v).Kind() == reflect.Ptr {
+		err := json.Unmarshal(b, v)
+		return v, err
+	}
+	ptr := reflect.New(reflect.TypeOf(v))
+	ptr.Elem().Set(reflect.ValueOf(v))
+	err := json.Unmarshal(b, ptr.Interface())
+	return ptr.Elem().Interface(), err
+}
+
+// encode marshals v into a JSON object, adding key with
+// the name that the type of v was registered with.
+func (r *registry) encode(key string, v interface{}) ([]byte, error) {
+	name, ok := r.name(v)
+	if !ok {
+		return nil, fmt.Errorf("unknown %s type: %T", r.kind, v)
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) < 2 || b[0] != '{' {
+		return nil, fmt.Errorf("%T does not marshal to a JSON object", v)
+	}
+	nb, err := json.Marshal(name)
+	if err != nil {
+		return nil, err
+	}
+	head := append([]byte(`{"`+key+`":`), nb...)
+	if len(b) > 2 {
+		head = append(head, ',')
+	}
+	return append(head, b[1:]...), nil
+}
@@ -0,0 +1,76 @@
+package checkup
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+type customChecker struct {
+	Name  string `json:"endpoint_name"`
+	Value int    `json:"value,omitempty"`
+}
+
+func (c customChecker) Check() (Result, error) {
+	return Result{Title: c.Name, Healthy: true}, nil
+}
+
+type customStorage struct {
+	Path string `json:"path"`
+}
+
+func (s *customStorage) Store(results []Result) error { return nil }
+
+func init() {
+	RegisterChecker("test:custom", func() Checker { return customChecker{} })
+	RegisterStorage("test:custom", func() Storage { return &customStorage{} })
+}
+
+func TestRegistryJSON(t *testing.T) {
+	jsonBytes := []byte(`{"storage":{"provider":"test:custom","path":"/tmp/checks"},"checkers":[{"type":"test:custom","endpoint_name":"Custom","value":3},{"type":"tcp","endpoint_name":"Example (TCP)","endpoint_url":"example.com:80"}],"timestamp":"0001-01-01T00:00:00Z"}`)
+
+	var c Checkup
+	err := json.Unmarshal(jsonBytes, &c)
+	if err != nil {
+		t.Fatalf("Error unmarshaling: %v", err)
+	}
+	if got, want := c.Checkers[0], (customChecker{Name: "Custom", Value: 3}); got != want {
+		t.Errorf("Expected checker %+v, got %+v", want, got)
+	}
+	if s, ok := c.Storage.(*customStorage); !ok || s.Path != "/tmp/checks" {
+		t.Errorf("Expected *customStorage with path, got %#v", c.Storage)
+	}
+
+	result, err := json.Marshal(c)
+	if err != nil {
+		t.Fatalf("Error marshaling: %v", err)
+	}
+	if !bytes.Equal(result, jsonBytes) {
+		t.Errorf("\nGot:  %s\nWant: %s", string(result), string(jsonBytes))
+	}
+}
+
+func TestRegistryUnknownType(t *testing.T) {
+	var c Checkup
+	err := json.Unmarshal([]byte(`{"checkers":[{"type":"nope"}]}`), &c)
+	if err == nil {
+		t.Fatal("Expected an error for an unknown checker type, didn't get one")
+	}
+	if got, want := err.Error(), "nope: unknown Checker type"; got != want {
+		t.Errorf(`Expected error "%s", got "%s"`, want, got)
+	}
+
+	_, err = json.Marshal(Checkup{Checkers: []Checker{new(fake)}})
+	if err == nil {
+		t.Error("Expected an error marshaling an unregistered checker, didn't get one")
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected registering a name twice to panic")
+		}
+	}()
+	RegisterChecker("http", func() Checker { return HTTPChecker{} })
+}
@@ -0,0 +1,141 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+)
+
+// Formats are the formats in which a Report can be written
+// with Write.
+var Formats = []string{"markdown", "csv", "html"}
+
+// Write writes r to w in format, which is one of Formats.
+func (r Report) Write(w io.Writer, format string) error {
+	switch format {
+	case "markdown":
+		return r.writeMarkdown(w)
+	case "csv":
+		return r.writeCSV(w)
+	case "html":
+		return htmlTemplate.Execute(w, r)
+	}
+	return fmt.Errorf("unknown format %q; want one of %s", format, strings.Join(Formats, ", "))
+}
+
+// columns are the headers of the columns of a report.
+var columns = []string{
+	"Endpoint", "URL", "Availability (%)", "Downtime (min)", "Maintenance (min)",
+	"Incidents", "MTTR", "Median (ms)", "P90 (ms)", "P95 (ms)", "P99 (ms)", "Checks",
+}
+
+// Row returns the columns of e, formatted as in the
+// reports written by Report.Write.
+func (e Endpoint) Row() []string {
+	return []string{
+		e.Title,
+		e.Endpoint,
+		e.AvailabilityText(),
+		minutes(e.Downtime),
+		minutes(e.Maintenance),
+		fmt.Sprint(e.Incidents),
+		e.MTTR.Round(time.Second).String(),
+		milliseconds(e.Median),
+		milliseconds(e.P90),
+		milliseconds(e.P95),
+		milliseconds(e.P99),
+		fmt.Sprint(e.Checks),
+	}
+}
+
+// AvailabilityText returns the availability of e as a
+// percentage with 3 decimals, or "n/a" if e was not
+// observed.
+func (e Endpoint) AvailabilityText() string {
+	if e.Observed == 0 {
+		return "n/a"
+	}
+	return fmt.Sprintf("%.3f", e.Availability)
+}
+
+func (r Report) writeMarkdown(w io.Writer) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# Availability report\n\n%s to %s\n\n", r.From.Format(time.RFC3339), r.To.Format(time.RFC3339))
+	cell := strings.NewReplacer("|", "\\|", "\n", " ")
+	b.WriteString("|")
+	for _, column := range columns {
+		b.WriteString(" " + column + " |")
+	}
+	b.WriteString("\n|")
+	for i := range columns {
+		if i < 2 {
+			b.WriteString(" --- |")
+		} else {
+			b.WriteString(" ---: |")
+		}
+	}
+	b.WriteString("\n")
+	for _, e := range r.Endpoints {
+		b.WriteString("|")
+		for _, v := range e.Row() {
+			b.WriteString(" " + cell.Replace(v) + " |")
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func (r Report) writeCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(columns); err != nil {
+		return err
+	}
+	for _, e := range r.Endpoints {
+		if err := cw.Write(e.Row()); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"columns": func() []string { return columns },
+	"rfc3339": func(t time.Time) string { return t.Format(time.RFC3339) },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Availability report</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; }
+td { text-align: right; }
+td:nth-child(-n+2) { text-align: left; }
+</style>
+</head>
+<body>
+<h1>Availability report</h1>
+<p>{{rfc3339 .From}} to {{rfc3339 .To}}</p>
+<table>
+<tr>{{range columns}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Endpoints}}
+<tr>{{range .Row}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+</body>
+</html>
+`))
+
+func minutes(d time.Duration) string {
+	return fmt.Sprintf("%.1f", d.Minutes())
+}
+
+func milliseconds(d time.Duration) string {
+	return fmt.Sprintf("%.1f", float64(d)/float64(time.Millisecond))
+}
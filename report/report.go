@@ -0,0 +1,195 @@
+// Package report computes uptime reports, such as for
+// service level agreements, from the results stored by
+// checkup.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Sparklane/checkup"
+)
+
+// Report is the availability of endpoints over a period.
+type Report struct {
+	From      time.Time  `json:"from"`
+	To        time.Time  `json:"to"`
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+// Endpoint is the availability of an endpoint over the
+// period of a report.
+//
+// Each result of the endpoint is taken to hold from its
+// check until the next check, or, for the latest result,
+// for as long as the checks are usually apart. Time spent
+// in maintenance is left out, so that it counts neither
+// as uptime nor as downtime. Endpoints that are degraded
+// are considered up.
+type Endpoint struct {
+	Title    string `json:"title"`
+	Endpoint string `json:"endpoint"`
+
+	// Checks is the number of results of the endpoint in
+	// the period, including those in maintenance.
+	Checks int `json:"checks"`
+
+	// Observed is how long the endpoint was checked for,
+	// outside of maintenance.
+	Observed time.Duration `json:"observed"`
+
+	// Maintenance is how long the endpoint was in
+	// maintenance.
+	Maintenance time.Duration `json:"maintenance"`
+
+	// Downtime is how long the endpoint was down.
+	Downtime time.Duration `json:"downtime"`
+
+	// Availability is the percentage of Observed during
+	// which the endpoint was up.
+	Availability float64 `json:"availability"`
+
+	// Incidents is the number of times the endpoint went
+	// down, and MTTR the mean time it took to recover, not
+	// counting maintenance during incidents.
+	Incidents int           `json:"incidents"`
+	MTTR      time.Duration `json:"mttr"`
+
+	// Median, P90, P95 and P99 are percentiles of the
+	// round trip times of the successful attempts.
+	Median time.Duration `json:"median"`
+	P90    time.Duration `json:"p90"`
+	P95    time.Duration `json:"p95"`
+	P99    time.Duration `json:"p99"`
+}
+
+// Compute reads the results checked between from and to in
+// reader and computes the availability of each endpoint.
+// When results of several locations were merged (see
+// checkup.MergeResults), only the merged results count.
+// Endpoints are sorted by title.
+func Compute(reader checkup.StorageReader, from, to time.Time) (Report, error) {
+	report := Report{From: from, To: to, Endpoints: []Endpoint{}}
+	if !to.After(from) {
+		return report, fmt.Errorf("invalid period: %s is not after %s", to, from)
+	}
+
+	index, err := reader.GetIndex()
+	if err != nil {
+		return report, fmt.Errorf("reading index: %v", err)
+	}
+	byEndpoint := make(map[string][]checkup.Result)
+	for name, ts := range index {
+		// check files are stored after their results
+		if ts < from.UnixNano() {
+			continue
+		}
+		results, err := reader.Fetch(name)
+		if err != nil {
+			return report, fmt.Errorf("fetching %s: %v", name, err)
+		}
+		for _, result := range results {
+			if result.Timestamp < from.UnixNano() || result.Timestamp > to.UnixNano() {
+				continue
+			}
+			key := strings.ToLower(result.Title)
+			byEndpoint[key] = append(byEndpoint[key], result)
+		}
+	}
+
+	for _, results := range byEndpoint {
+		report.Endpoints = append(report.Endpoints, computeEndpoint(onlyMerged(results), to))
+	}
+	sort.Slice(report.Endpoints, func(i, j int) bool {
+		return strings.ToLower(report.Endpoints[i].Title) < strings.ToLower(report.Endpoints[j].Title)
+	})
+	return report, nil
+}
+
+// onlyMerged returns the merged results among results, if
+// there are any, or results otherwise.
+func onlyMerged(results []checkup.Result) []checkup.Result {
+	var merged []checkup.Result
+	for _, result := range results {
+		if result.Locations != nil {
+			merged = append(merged, result)
+		}
+	}
+	if len(merged) == 0 {
+		return results
+	}
+	return merged
+}
+
+// computeEndpoint computes the availability of an endpoint
+// from its results, until to.
+func computeEndpoint(results []checkup.Result, to time.Time) Endpoint {
+	sort.Slice(results, func(i, j int) bool { return results[i].Timestamp < results[j].Timestamp })
+	latest := results[len(results)-1]
+	e := Endpoint{Title: latest.Title, Endpoint: latest.Endpoint, Checks: len(results)}
+
+	spans := spansOf(results, to.UnixNano())
+	var rtts checkup.Attempts
+	var down bool
+	for i, result := range results {
+		span := spans[i]
+		if result.Maintenance {
+			// maintenance pauses the clock of an incident
+			e.Maintenance += span
+			continue
+		}
+		e.Observed += span
+		if result.Down {
+			e.Downtime += span
+			if !down {
+				down = true
+				e.Incidents++
+			}
+		} else {
+			down = false
+		}
+		for _, attempt := range result.Times {
+			if attempt.Error == "" {
+				rtts = append(rtts, attempt)
+			}
+		}
+	}
+	if e.Incidents > 0 {
+		// incidents last as long as their endpoint is down,
+		// not counting maintenance, until it recovers or, for
+		// the latest, until the end of its span
+		e.MTTR = e.Downtime / time.Duration(e.Incidents)
+	}
+	if e.Observed > 0 {
+		e.Availability = 100 * float64(e.Observed-e.Downtime) / float64(e.Observed)
+	}
+
+	stats := checkup.Result{Times: rtts}.ComputeStats()
+	e.Median, e.P90, e.P95, e.P99 = stats.Median, stats.P90, stats.P95, stats.P99
+	return e
+}
+
+// spansOf returns how long each of results, sorted by
+// time, holds: until the next result, or, for the latest,
+// for the median time between results, but not past to.
+func spansOf(results []checkup.Result, to int64) []time.Duration {
+	spans := make([]time.Duration, len(results))
+	gaps := make([]int64, 0, len(results))
+	for i := 0; i < len(results)-1; i++ {
+		gap := results[i+1].Timestamp - results[i].Timestamp
+		spans[i] = time.Duration(gap)
+		gaps = append(gaps, gap)
+	}
+	if len(gaps) > 0 {
+		sort.Slice(gaps, func(i, j int) bool { return gaps[i] < gaps[j] })
+		last := results[len(results)-1].Timestamp
+		end := last + gaps[len(gaps)/2]
+		if end > to {
+			end = to
+		}
+		spans[len(spans)-1] = time.Duration(end - last)
+	}
+	return spans
+}
@@ -0,0 +1,169 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Sparklane/checkup"
+)
+
+// memory is a StorageReader of results held in memory,
+// one check file per result.
+type memory []checkup.Result
+
+func (m memory) GetIndex() (map[string]int64, error) {
+	index := make(map[string]int64)
+	for i, result := range m {
+		index[fmt.Sprint(i)] = result.Timestamp
+	}
+	return index, nil
+}
+
+func (m memory) Fetch(name string) ([]checkup.Result, error) {
+	var i int
+	if _, err := fmt.Sscan(name, &i); err != nil || i >= len(m) {
+		return nil, fmt.Errorf("no check file %s", name)
+	}
+	return []checkup.Result{m[i]}, nil
+}
+
+var start = time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+
+// results returns the results of title checked every 10
+// minutes from start, with the given statuses: h for
+// healthy, d for degraded, D for down and m for maintenance.
+func results(title, statuses string) []checkup.Result {
+	var results []checkup.Result
+	for i, status := range statuses {
+		r := checkup.Result{
+			Title:     title,
+			Endpoint:  "https://" + strings.ToLower(title),
+			Timestamp: start.Add(time.Duration(i) * 10 * time.Minute).UnixNano(),
+			Times:     checkup.Attempts{{RTT: time.Duration(i+1) * time.Millisecond}},
+		}
+		switch status {
+		case 'h':
+			r.Healthy = true
+		case 'd':
+			r.Degraded = true
+		case 'D':
+			r.Down = true
+			r.Times[0].Error = "connection refused"
+		case 'm':
+			r.Down, r.Maintenance = true, true
+		}
+		results = append(results, r)
+	}
+	return results
+}
+
+func TestCompute(t *testing.T) {
+	var storage memory
+	storage = append(storage, results("Web", "hhDDhhmmhDhh")...)
+	storage = append(storage, results("API", "hdhh")...)
+	// out of the period
+	storage = append(storage, checkup.Result{Title: "Web", Timestamp: start.Add(-time.Hour).UnixNano(), Down: true})
+
+	report, err := Compute(storage, start, start.Add(24*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Endpoints) != 2 || report.Endpoints[0].Title != "API" {
+		t.Fatalf("Expected API and Web, got %+v", report.Endpoints)
+	}
+
+	api, web := report.Endpoints[0], report.Endpoints[1]
+	if api.Availability != 100 || api.Incidents != 0 || api.Observed != 40*time.Minute {
+		t.Errorf("Expected API to be available for 40m, got %+v", api)
+	}
+	if web.Checks != 12 {
+		t.Errorf("Expected 12 checks of Web, got %d", web.Checks)
+	}
+	if web.Observed != 100*time.Minute || web.Maintenance != 20*time.Minute || web.Downtime != 30*time.Minute {
+		t.Errorf("Expected Web to be observed 100m, with 20m of maintenance and 30m of downtime, got %s, %s and %s",
+			web.Observed, web.Maintenance, web.Downtime)
+	}
+	if web.Availability != 70 {
+		t.Errorf("Expected Web to be available 70%%, got %v", web.Availability)
+	}
+	if web.Incidents != 2 || web.MTTR != 15*time.Minute {
+		t.Errorf("Expected 2 incidents with a MTTR of 15m, got %d and %s", web.Incidents, web.MTTR)
+	}
+	if web.P99 != 12*time.Millisecond || web.Median != 6*time.Millisecond {
+		t.Errorf("Expected the percentiles of successful attempts, got median %s and p99 %s", web.Median, web.P99)
+	}
+
+	if _, err := Compute(storage, start, start); err == nil {
+		t.Error("Expected an error for an empty period")
+	}
+}
+
+func TestComputeMaintenanceDuringIncident(t *testing.T) {
+	report, err := Compute(memory(results("Web", "hDmmmDhh")), start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := report.Endpoints[0]; e.Incidents != 1 || e.MTTR != 20*time.Minute {
+		t.Errorf("Expected 1 incident with a MTTR of 20m, not counting maintenance, got %d and %s", e.Incidents, e.MTTR)
+	}
+}
+
+func TestComputeMerged(t *testing.T) {
+	storage := memory(results("Web", "DDhh"))
+	merged := results("Web", "hhhh")
+	for i := range merged {
+		merged[i].Locations = map[string]checkup.StatusText{"paris": checkup.Healthy}
+	}
+	storage = append(storage, merged...)
+
+	report, err := Compute(storage, start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e := report.Endpoints[0]; e.Checks != 4 || e.Downtime != 0 {
+		t.Errorf("Expected only the merged results to count, got %+v", e)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	report, err := Compute(memory(results("Web|Front", "hhDh")), start, start.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf, "markdown"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "| Web\\|Front | https://web\\|front | 75.000 | 10.0 | 0.0 | 1 | 10m0s |"; !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected markdown to contain %q, got:\n%s", want, buf.String())
+	}
+
+	buf.Reset()
+	if err := report.Write(&buf, "csv"); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[1][0] != "Web|Front" || records[1][2] != "75.000" {
+		t.Errorf("Expected a header and a row, got %v", records)
+	}
+
+	buf.Reset()
+	if err := report.Write(&buf, "html"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<td>Web|Front</td><td>https://web|front</td><td>75.000</td>"; !strings.Contains(buf.String(), want) {
+		t.Errorf("Expected HTML to contain %q, got:\n%s", want, buf.String())
+	}
+
+	if err := report.Write(&buf, "pdf"); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,196 @@
+package checkup
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule is a condition on the statistics of a result, such
+// as "p95 > 300ms" or "failures > 1". A condition compares
+// a statistic with a value using >, >=, < or <=. Several
+// conditions can be combined with "or"; the rule is met if
+// any of them is.
+//
+// The statistics are min, max, mean, median, p90, p95, p99
+// and stddev, which are compared with durations such as
+// "300ms", and failures and failure_ratio, which are
+// compared with numbers. See Stats.
+//
+// In JSON, a Rule is the text it is parsed from; it is
+// parsed as it is unmarshaled.
+type Rule struct {
+	conditions []condition
+	text       string
+}
+
+type condition struct {
+	stat  string
+	op    string
+	value float64
+	text  string
+}
+
+// durationStats are the statistics that are durations.
+var durationStats = map[string]func(Stats) time.Duration{
+	"min":    func(s Stats) time.Duration { return s.Min },
+	"max":    func(s Stats) time.Duration { return s.Max },
+	"mean":   func(s Stats) time.Duration { return s.Mean },
+	"median": func(s Stats) time.Duration { return s.Median },
+	"p90":    func(s Stats) time.Duration { return s.P90 },
+	"p95":    func(s Stats) time.Duration { return s.P95 },
+	"p99":    func(s Stats) time.Duration { return s.P99 },
+	"stddev": func(s Stats) time.Duration { return s.StdDev },
+}
+
+// numberStats are the statistics that are numbers.
+var numberStats = map[string]func(Stats) float64{
+	"failures":      func(s Stats) float64 { return float64(s.Failures) },
+	"failure_ratio": func(s Stats) float64 { return s.FailureRatio },
+}
+
+// ruleOr separates the conditions of a rule.
+var ruleOr = regexp.MustCompile(`(?i)\s+or\s+`)
+
+// ParseRule parses a rule such as "p95 > 300ms or
+// failures > 1". See Rule.
+func ParseRule(s string) (Rule, error) {
+	r := Rule{text: strings.TrimSpace(s)}
+	for _, text := range ruleOr.Split(s, -1) {
+		fields := strings.Fields(text)
+		if len(fields) != 3 {
+			return r, fmt.Errorf("invalid rule %q: want <stat> <op> <value>", text)
+		}
+		c := condition{stat: strings.ToLower(fields[0]), op: fields[1], text: strings.Join(fields, " ")}
+		switch c.op {
+		case ">", ">=", "<", "<=":
+		default:
+			return r, fmt.Errorf("invalid rule %q: unknown operator %q", text, c.op)
+		}
+		if _, ok := durationStats[c.stat]; ok {
+			d, err := time.ParseDuration(fields[2])
+			if err != nil {
+				return r, fmt.Errorf("invalid rule %q: %v", text, err)
+			}
+			c.value = float64(d)
+		} else if _, ok := numberStats[c.stat]; ok {
+			v, err := strconv.ParseFloat(fields[2], 64)
+			if err != nil {
+				return r, fmt.Errorf("invalid rule %q: invalid number %q", text, fields[2])
+			}
+			c.value = v
+		} else {
+			return r, fmt.Errorf("invalid rule %q: unknown statistic %q", text, fields[0])
+		}
+		r.conditions = append(r.conditions, c)
+	}
+	return r, nil
+}
+
+// String returns the text r was parsed from.
+func (r Rule) String() string {
+	return r.text
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (r Rule) MarshalText() ([]byte, error) {
+	return []byte(r.text), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. An
+// empty text leaves r without conditions, as if unset.
+func (r *Rule) UnmarshalText(text []byte) error {
+	if strings.TrimSpace(string(text)) == "" {
+		*r = Rule{}
+		return nil
+	}
+	rule, err := ParseRule(string(text))
+	if err != nil {
+		return err
+	}
+	*r = rule
+	return nil
+}
+
+// set returns whether r is a rule with conditions.
+func (r *Rule) set() bool {
+	return r != nil && len(r.conditions) > 0
+}
+
+// Match returns whether stats meet r, and if so, a
+// description of the condition that is met, such as
+// "p95 > 300ms (412ms)".
+func (r Rule) Match(stats Stats) (bool, string) {
+	for _, c := range r.conditions {
+		var v float64
+		var shown string
+		if f, ok := durationStats[c.stat]; ok {
+			d := f(stats)
+			v, shown = float64(d), d.String()
+		} else {
+			v = numberStats[c.stat](stats)
+			shown = strconv.FormatFloat(v, 'g', -1, 64)
+		}
+		var met bool
+		switch c.op {
+		case ">":
+			met = v > c.value
+		case ">=":
+			met = v >= c.value
+		case "<":
+			met = v < c.value
+		case "<=":
+			met = v <= c.value
+		}
+		if met {
+			return true, fmt.Sprintf("%s (%s)", c.text, shown)
+		}
+	}
+	return false, ""
+}
+
+// down returns whether a result with stats is down
+// according to o.DownOn, or, by default, whether any
+// attempt failed.
+func (o CheckerOptions) down(stats Stats) bool {
+	if !o.DownOn.set() {
+		return stats.Failures > 0
+	}
+	met, _ := o.DownOn.Match(stats)
+	return met
+}
+
+// degraded returns whether a result with stats is degraded
+// according to o.DegradeOn, or, by default, whether its
+// median round trip time exceeds threshold, if set. If so,
+// it also returns a notice explaining why.
+func (o CheckerOptions) degraded(stats Stats, threshold time.Duration) (bool, string) {
+	if !o.DegradeOn.set() {
+		if threshold > 0 && stats.Median > threshold {
+			return true, fmt.Sprintf("median round trip time exceeded threshold (%s)", threshold)
+		}
+		return false, ""
+	}
+	met, why := o.DegradeOn.Match(stats)
+	if !met {
+		return false, ""
+	}
+	return true, "degraded: " + why
+}
+
+// validate returns an error if the time zone or the
+// maintenance windows of o are invalid. Its rules are
+// checked as they are unmarshaled.
+func (o CheckerOptions) validate() error {
+	if _, err := o.location(); err != nil {
+		return err
+	}
+	for _, w := range o.Maintenance {
+		if err := w.validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
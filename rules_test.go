@@ -0,0 +1,105 @@
+package checkup
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestRule(t *testing.T) {
+	stats := Stats{
+		Median:       100 * time.Millisecond,
+		P95:          400 * time.Millisecond,
+		Failures:     1,
+		FailureRatio: 0.2,
+	}
+
+	for i, test := range []struct {
+		rule string
+		met  bool
+		why  string
+	}{
+		{"p95 > 300ms", true, "p95 > 300ms (400ms)"},
+		{"p95 > 500ms", false, ""},
+		{"median >= 100ms", true, "median >= 100ms (100ms)"},
+		{"failures > 1", false, ""},
+		{"failures > 1 or p95 > 300ms", true, "p95 > 300ms (400ms)"},
+		{"failure_ratio >= 0.2", true, "failure_ratio >= 0.2 (0.2)"},
+		{"P95  <  1s", true, "P95 < 1s (400ms)"},
+		{"failures > 1 OR p95 > 300ms", true, "p95 > 300ms (400ms)"},
+	} {
+		rule, err := ParseRule(test.rule)
+		if err != nil {
+			t.Errorf("Test %d: Didn't expect an error: %v", i, err)
+			continue
+		}
+		met, why := rule.Match(stats)
+		if met != test.met || why != test.why {
+			t.Errorf("Test %d: Expected %q to be met=%v (%q), got %v (%q)", i, test.rule, test.met, test.why, met, why)
+		}
+	}
+
+	for i, rule := range []string{
+		"",
+		"p95 > ",
+		"p42 > 1s",
+		"p95 = 1s",
+		"p95 > 300",
+		"failures > one",
+		"failures > 1 or",
+	} {
+		if _, err := ParseRule(rule); err == nil {
+			t.Errorf("Test %d: Expected an error for %q, didn't get one", i, rule)
+		}
+	}
+}
+
+func TestCheckerOptionsRules(t *testing.T) {
+	one := Stats{Failures: 1, FailureRatio: 0.2, Median: time.Second}
+
+	var o CheckerOptions
+	if !o.down(one) {
+		t.Error("Expected any failure to be down by default")
+	}
+	if degraded, _ := o.degraded(one, 2*time.Second); degraded {
+		t.Error("Expected median below threshold not to be degraded by default")
+	}
+
+	down, _ := ParseRule("failures > 1")
+	degrade, _ := ParseRule("failures > 0")
+	o = CheckerOptions{DownOn: &down, DegradeOn: &degrade}
+	if o.down(one) {
+		t.Error("Expected a single failure not to be down with down_on")
+	}
+	if degraded, notice := o.degraded(one, 0); !degraded || notice != "degraded: failures > 0 (1)" {
+		t.Errorf("Expected a single failure to be degraded with degrade_on, got %v (%q)", degraded, notice)
+	}
+}
+
+func TestJSONRule(t *testing.T) {
+	var o CheckerOptions
+	if err := json.Unmarshal([]byte(`{"down_on":"failures > 1 or p95 > 1s","degrade_on":""}`), &o); err != nil {
+		t.Fatalf("Didn't expect an error: %v", err)
+	}
+	if o.DegradeOn.set() {
+		t.Error("Expected an empty rule to be unset")
+	}
+	if met, _ := o.DownOn.Match(Stats{Failures: 2}); !met {
+		t.Error("Expected the rule to be parsed as unmarshaled")
+	}
+	b, err := json.Marshal(CheckerOptions{DownOn: o.DownOn})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := string(b), `{"down_on":"failures \u003e 1 or p95 \u003e 1s"}`; got != want {
+		t.Errorf("Expected %s, got %s", want, got)
+	}
+}
+
+func TestJSONInvalidRule(t *testing.T) {
+	jsonBytes := []byte(`{"checkers":[{"type":"tcp","endpoint_name":"a","endpoint_url":"example.com:80","degrade_on":"p95 > fast"}]}`)
+	var c Checkup
+	if err := json.Unmarshal(jsonBytes, &c); err == nil {
+		t.Error("Expected an error for an invalid rule, didn't get one")
+	}
+}
@@ -0,0 +1,213 @@
+package checkup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+// Schedule determines when a checker runs.
+type Schedule interface {
+	// Next returns the next time to run after t.
+	Next(t time.Time) time.Time
+}
+
+// Every returns a Schedule that runs every interval,
+// starting immediately.
+func Every(interval time.Duration) Schedule {
+	return every(interval)
+}
+
+type every time.Duration
+
+func (e every) Next(t time.Time) time.Time {
+	return t.Add(time.Duration(e))
+}
+
+// ParseSchedule returns the Schedule described by spec,
+// which is a standard 5-field cron expression (minute,
+// hour, day of month, month, day of week) or a descriptor
+// such as "@daily" or "@every 1h30m". Times of day are
+// evaluated in loc, unless spec starts with a
+// "CRON_TZ=<zone> " prefix. If loc is nil, the local time
+// zone is used.
+func ParseSchedule(spec string, loc *time.Location) (Schedule, error) {
+	sched, err := cron.ParseStandard(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %v", spec, err)
+	}
+	spec = strings.TrimSpace(spec)
+	explicit := strings.HasPrefix(spec, "CRON_TZ=") || strings.HasPrefix(spec, "TZ=")
+	if s, ok := sched.(*cron.SpecSchedule); ok && loc != nil && !explicit {
+		s.Location = loc
+	}
+	return sched, nil
+}
+
+// scheduleOf returns the schedule of checker, which is
+// def unless the checker has its own interval or schedule.
+func scheduleOf(checker Checker, def Schedule) (Schedule, error) {
+	opts := optionsOf(checker)
+	switch {
+	case opts.Interval > 0 && opts.Schedule != "":
+		return nil, fmt.Errorf("both interval and schedule are set")
+	case opts.Interval > 0:
+		return Every(opts.Interval), nil
+	case opts.Schedule != "":
+		loc, err := opts.location()
+		if err != nil {
+			return nil, err
+		}
+		return ParseSchedule(opts.Schedule, loc)
+	}
+	return def, nil
+}
+
+// CheckAndStoreScheduled runs each checker of c on its own
+// schedule, which is schedule unless the checker sets its
+// own interval or schedule, and stores the results to the configured
+// storage. Checkers that are due at the same time are run
+// together: their results are stored in a single call to
+// Store(), after which Maintain() is called if c.Storage is
+// a Maintainer, and then passed to the notifiers. If c.RunTimeout is
+// not set, a run must complete before any of its checkers
+// is due again. The maintenance scheduled in c.Storage is
+// read at most once a minute. Errors are written to the
+// standard logger.
+//
+// CheckAndStoreScheduled blocks until ctx is done and the
+// runs in progress have completed.
+func (c Checkup) CheckAndStoreScheduled(ctx context.Context, schedule Schedule) error {
+	if c.Storage == nil {
+		return fmt.Errorf("no storage mechanism defined")
+	}
+	if len(c.Checkers) == 0 {
+		return fmt.Errorf("no checkers configured")
+	}
+
+	if err := checkDependencies(c.Checkers); err != nil {
+		return err
+	}
+	if c.states == nil {
+		c.states = newStates()
+	}
+	if c.maintenance == nil {
+		c.maintenance = &maintenanceCache{}
+	}
+
+	schedules := make([]Schedule, len(c.Checkers))
+	next := make([]time.Time, len(c.Checkers))
+	start := time.Now()
+	for i, checker := range c.Checkers {
+		var err error
+		schedules[i], err = scheduleOf(checker, schedule)
+		if err != nil {
+			return fmt.Errorf("checker %d: %v", i, err)
+		}
+		if _, ok := schedules[i].(every); ok {
+			next[i] = start
+		} else {
+			next[i] = schedules[i].Next(start)
+		}
+	}
+
+	var mu sync.Mutex // serializes notifying and storing
+	latest := make(map[string]Result)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		due := next[0]
+		for _, t := range next[1:] {
+			if t.Before(due) {
+				due = t
+			}
+		}
+		timer := time.NewTimer(time.Until(due))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		now := time.Now()
+		run := c.WithoutNotifiers()
+		run.Checkers = nil
+		var deadline time.Time
+		for i, checker := range c.Checkers {
+			if next[i].After(now) {
+				continue
+			}
+			run.Checkers = append(run.Checkers, checker)
+			// stay on the schedule's grid, so that checkers
+			// due together keep being run together
+			for !next[i].After(now) {
+				n := schedules[i].Next(next[i])
+				if !n.After(next[i]) {
+					return fmt.Errorf("schedule of checker %d does not advance", i)
+				}
+				next[i] = n
+			}
+			if deadline.IsZero() || next[i].Before(deadline) {
+				deadline = next[i]
+			}
+		}
+		if run.RunTimeout == 0 {
+			run.RunTimeout = deadline.Sub(now)
+		}
+
+		wg.Add(1)
+		go func(run Checkup) {
+			defer wg.Done()
+			results, err := run.CheckContext(ctx)
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			// dependencies that weren't due are evaluated
+			// with their latest results
+			for _, result := range results {
+				latest[strings.ToLower(result.Title)] = result
+			}
+			if err := markUnreachable(run.Checkers, results, latest); err != nil {
+				log.Println(err)
+				return
+			}
+			if err := c.store(ctx, results); err != nil {
+				log.Println(err)
+			}
+		}(run)
+	}
+}
+
+// store stores results and performs maintenance on c.Storage if it is
+// a Maintainer, then notifies the notifiers of c of results, until ctx
+// is done. Results are notified even if they can't be stored, and
+// stored even if notifiers fail; their errors are returned together.
+func (c Checkup) store(ctx context.Context, results []Result) error {
+	var errs Errors
+	if err := c.Storage.Store(results); err != nil {
+		c.Metrics.storageError()
+		errs = append(errs, err)
+	} else if m, ok := c.Storage.(Maintainer); ok {
+		if err := m.Maintain(); err != nil {
+			c.Metrics.storageError()
+			errs = append(errs, err)
+		}
+	}
+	if err := c.notify(ctx, results); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
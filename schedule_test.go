@@ -0,0 +1,190 @@
+package checkup
+
+import (
+	"context"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCheckAndStoreScheduled(t *testing.T) {
+	s := new(recorder)
+	c := Checkup{
+		Checkers: []Checker{
+			named{Name: "fast"},
+			named{Name: "slow", CheckerOptions: CheckerOptions{Interval: 100 * time.Millisecond}},
+		},
+		Storage:   s,
+		Notifier:  s,
+		Notifiers: []Notifier{s},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 230*time.Millisecond)
+	defer cancel()
+	err := c.CheckAndStoreScheduled(ctx, Every(50*time.Millisecond))
+	if err != context.DeadlineExceeded {
+		t.Errorf("Expected the context error, got: %v", err)
+	}
+
+	s.Lock()
+	defer s.Unlock()
+	// fast runs at 0, 50, 100, 150 and 200ms; slow at 0, 100 and 200ms
+	if got, want := s.checks["fast"], 5; got != want {
+		t.Errorf("Expected fast checker to run %d times, ran %d times", want, got)
+	}
+	if got, want := s.checks["slow"], 3; got != want {
+		t.Errorf("Expected slow checker to run %d times, ran %d times", want, got)
+	}
+	if got, want := s.stored, 5; got != want {
+		t.Errorf("Expected Store() to be called %d times, called %d times", want, got)
+	}
+	// once by Notifier and once as one of Notifiers
+	if got, want := s.notified, 2*s.stored; got != want {
+		t.Errorf("Expected Notify() to be called %d times, called %d times", want, got)
+	}
+	if got, want := s.largest, 2; got != want {
+		t.Errorf("Expected results due together to be stored together (%d), largest batch was %d", want, got)
+	}
+}
+
+func TestCheckAndStoreScheduledNoStorage(t *testing.T) {
+	c := Checkup{Checkers: []Checker{named{Name: "a"}}}
+	if err := c.CheckAndStoreScheduled(context.Background(), Every(time.Second)); err == nil {
+		t.Error("Expected an error with no storage, didn't get one")
+	}
+}
+
+func TestParseSchedule(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skipf("time zone database unavailable: %v", err)
+	}
+	from := time.Date(2019, 10, 1, 8, 0, 0, 0, time.UTC) // 10:00 in Paris
+
+	for i, test := range []struct {
+		spec string
+		loc  *time.Location
+		want time.Time
+	}{
+		{"0 7 * * *", paris, time.Date(2019, 10, 2, 5, 0, 0, 0, time.UTC)},
+		{"0 7 * * *", time.UTC, time.Date(2019, 10, 2, 7, 0, 0, 0, time.UTC)},
+		{"CRON_TZ=UTC 0 7 * * *", paris, time.Date(2019, 10, 2, 7, 0, 0, 0, time.UTC)},
+		{"*/5 * * * *", paris, time.Date(2019, 10, 1, 8, 5, 0, 0, time.UTC)},
+		{"@every 90m", paris, time.Date(2019, 10, 1, 9, 30, 0, 0, time.UTC)},
+	} {
+		s, err := ParseSchedule(test.spec, test.loc)
+		if err != nil {
+			t.Errorf("Test %d: Didn't expect an error: %v", i, err)
+			continue
+		}
+		if got := s.Next(from); !got.Equal(test.want) {
+			t.Errorf("Test %d: Expected next run of %q at %s, got %s", i, test.spec, test.want, got.UTC())
+		}
+	}
+
+	if _, err := ParseSchedule("61 * * * *", nil); err == nil {
+		t.Error("Expected an error for an invalid schedule, didn't get one")
+	}
+}
+
+func TestScheduleOf(t *testing.T) {
+	def := Every(time.Minute)
+
+	s, err := scheduleOf(named{}, def)
+	if err != nil || s != def {
+		t.Errorf("Expected the default schedule, got %v (error: %v)", s, err)
+	}
+	s, err = scheduleOf(new(fake), def)
+	if err != nil || s != def {
+		t.Errorf("Expected the default schedule for a checker without options, got %v (error: %v)", s, err)
+	}
+
+	_, err = scheduleOf(named{CheckerOptions: CheckerOptions{Interval: time.Hour, Schedule: "@daily"}}, def)
+	if err == nil {
+		t.Error("Expected an error with both interval and schedule set, didn't get one")
+	}
+	_, err = scheduleOf(named{CheckerOptions: CheckerOptions{Schedule: "@daily", Timezone: "Nowhere/Atlantis"}}, def)
+	if err == nil {
+		t.Error("Expected an error with an invalid timezone, didn't get one")
+	}
+}
+
+func TestCheckerLocation(t *testing.T) {
+	tz, set := os.LookupEnv("TZ")
+	defer func() {
+		if set {
+			os.Setenv("TZ", tz)
+		} else {
+			os.Unsetenv("TZ")
+		}
+	}()
+
+	os.Unsetenv("TZ")
+	loc, err := CheckerOptions{}.location()
+	if err != nil || loc != time.Local {
+		t.Errorf("Expected the local time zone by default, got %v (error: %v)", loc, err)
+	}
+	loc, err = CheckerOptions{}.ignoreLocation()
+	if err != nil || loc.String() != "Europe/Paris" {
+		t.Errorf("Expected Europe/Paris for ignore_times by default, got %v (error: %v)", loc, err)
+	}
+	os.Setenv("TZ", "America/New_York")
+	loc, err = CheckerOptions{}.ignoreLocation()
+	if err != nil || loc != time.Local {
+		t.Errorf("Expected the TZ time zone for ignore_times, got %v (error: %v)", loc, err)
+	}
+	loc, err = CheckerOptions{Timezone: "Asia/Tokyo"}.location()
+	if err != nil || loc.String() != "Asia/Tokyo" {
+		t.Errorf("Expected the configured time zone, got %v (error: %v)", loc, err)
+	}
+}
+
+// named is a Checker that reports a result titled Name,
+// which is healthy unless Down is set.
+type named struct {
+	Name string `json:"endpoint_name"`
+	Down bool   `json:"down,omitempty"`
+	CheckerOptions
+}
+
+func (n named) Title() string {
+	return n.Name
+}
+
+func (n named) Check() (Result, error) {
+	return Result{Title: n.Name, Timestamp: Timestamp(), Healthy: !n.Down, Down: n.Down}, nil
+}
+
+// recorder is a Storage and Notifier that records
+// what it is given.
+type recorder struct {
+	sync.Mutex
+	checks   map[string]int
+	stored   int
+	notified int
+	largest  int
+}
+
+func (r *recorder) Store(results []Result) error {
+	r.Lock()
+	defer r.Unlock()
+	if r.checks == nil {
+		r.checks = make(map[string]int)
+	}
+	for _, result := range results {
+		r.checks[result.Title]++
+	}
+	r.stored++
+	if len(results) > r.largest {
+		r.largest = len(results)
+	}
+	return nil
+}
+
+func (r *recorder) Notify(results []Result) error {
+	r.Lock()
+	defer r.Unlock()
+	r.notified++
+	return nil
+}
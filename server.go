@@ -0,0 +1,325 @@
+package checkup
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DefaultHistory is how far back the history of an
+// endpoint goes when served without a "since" parameter.
+const DefaultHistory = 24 * time.Hour
+
+// Server serves the status page, the check files of a
+// StorageReader and a JSON API over HTTP:
+//
+//	/check_files/index.json          the storage index
+//	/check_files/<name>              a check file
+//	/check_files/incidents.json      the incidents
+//	/check_files/maintenance.json    the scheduled maintenance
+//	/api/status                      the latest result of each endpoint
+//	/api/endpoints/<title>/history   the results of an endpoint
+//	/api/incidents                   the incidents, newest first
+//	/api/maintenance                 the scheduled maintenance, by start time
+//	/metrics                         Prometheus metrics, if Metrics is set
+//
+// The history can be limited with a "since" parameter, such
+// as "?since=1h"; default is DefaultHistory. Any other path
+// is looked up in StatusPage, if set.
+//
+// Check files are cached in memory once read, as they are
+// never modified once stored.
+type Server struct {
+	// Storage is where results are read from.
+	Storage StorageReader
+
+	// StatusPage holds the files of the status page. Its
+	// js/config.js should point the storage url to
+	// /check_files.
+	StatusPage http.FileSystem
+
+	// Metrics, if set, is served at /metrics, along with
+	// the latest results in Storage.
+	Metrics *Metrics
+
+	mu    sync.Mutex
+	cache map[string][]Result
+}
+
+// EndpointStatus is the latest result of an endpoint, as
+// served by the /api/status endpoint of a Server.
+type EndpointStatus struct {
+	Result
+	Status StatusText `json:"status"`
+}
+
+// StatusReport is the status of all endpoints, as served
+// by the /api/status endpoint of a Server.
+type StatusReport struct {
+	// Status is the overall status: the status of highest
+	// priority among the endpoints. See PriorityOver.
+	Status    StatusText       `json:"status"`
+	Timestamp int64            `json:"timestamp"`
+	Endpoints []EndpointStatus `json:"endpoints"`
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	path := r.URL.EscapedPath()
+	switch {
+	case path == "/check_files/index.json":
+		s.serveIndex(w, r)
+	case path == "/check_files/"+IncidentsDocument, path == "/api/incidents":
+		s.serveIncidents(w, r)
+	case path == "/check_files/"+MaintenanceDocument, path == "/api/maintenance":
+		s.serveMaintenance(w, r)
+	case strings.HasPrefix(path, "/check_files/"):
+		name, err := url.PathUnescape(strings.TrimPrefix(path, "/check_files/"))
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		s.serveCheckFile(w, r, name)
+	case path == "/metrics" && s.Metrics != nil:
+		s.serveMetrics(w, r)
+	case path == "/api/status":
+		s.serveStatus(w, r)
+	case strings.HasPrefix(path, "/api/endpoints/") && strings.HasSuffix(path, "/history"):
+		title := strings.TrimSuffix(strings.TrimPrefix(path, "/api/endpoints/"), "/history")
+		title, err := url.PathUnescape(title)
+		if err != nil || title == "" {
+			http.NotFound(w, r)
+			return
+		}
+		s.serveHistory(w, r, title)
+	case strings.HasPrefix(path, "/api/"):
+		http.NotFound(w, r)
+	case s.StatusPage != nil:
+		http.FileServer(s.StatusPage).ServeHTTP(w, r)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveIndex(w http.ResponseWriter, r *http.Request) {
+	index, err := s.index()
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, index)
+}
+
+func (s *Server) serveCheckFile(w http.ResponseWriter, r *http.Request, name string) {
+	index, err := s.index()
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	// only check files in the index are served
+	if _, ok := index[name]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+	results, err := s.fetch(name)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, results)
+}
+
+func (s *Server) serveStatus(w http.ResponseWriter, r *http.Request) {
+	latest, err := s.latest()
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	merged := make(map[string]bool)
+	for _, result := range latest {
+		if result.Locations != nil {
+			merged[strings.ToLower(result.Title)] = true
+		}
+	}
+
+	report := StatusReport{Status: Unknown, Endpoints: []EndpointStatus{}}
+	for _, result := range latest {
+		status := result.Status()
+		report.Endpoints = append(report.Endpoints, EndpointStatus{Result: result, Status: status})
+		if result.Timestamp > report.Timestamp {
+			report.Timestamp = result.Timestamp
+		}
+		// the results of a location don't count when merged
+		if result.location() != "" && merged[strings.ToLower(result.Title)] {
+			continue
+		}
+		if status.PriorityOver(report.Status) {
+			report.Status = status
+		}
+	}
+	writeJSON(w, report)
+}
+
+func (s *Server) serveIncidents(w http.ResponseWriter, r *http.Request) {
+	storage, ok := s.Storage.(DocumentStorage)
+	if !ok {
+		writeJSON(w, []Incident{})
+		return
+	}
+	incidents, err := ReadIncidents(storage)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, incidents)
+}
+
+func (s *Server) serveMaintenance(w http.ResponseWriter, r *http.Request) {
+	storage, ok := s.Storage.(DocumentStorage)
+	if !ok {
+		writeJSON(w, []ScheduledMaintenance{})
+		return
+	}
+	scheduled, err := ReadMaintenance(storage)
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	writeJSON(w, scheduled)
+}
+
+func (s *Server) serveHistory(w http.ResponseWriter, r *http.Request, title string) {
+	since := DefaultHistory
+	if v := r.URL.Query().Get("since"); v != "" {
+		var err error
+		since, err = time.ParseDuration(v)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid since: %v", err), http.StatusBadRequest)
+			return
+		}
+	}
+	after := time.Now().Add(-since).UnixNano()
+
+	index, err := s.index()
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	history := []Result{}
+	for name, ts := range index {
+		if ts < after {
+			continue
+		}
+		results, err := s.fetch(name)
+		if err != nil {
+			serverError(w, err)
+			return
+		}
+		for _, result := range results {
+			if strings.EqualFold(result.Title, title) && result.Timestamp >= after {
+				history = append(history, result)
+			}
+		}
+	}
+	sort.Slice(history, func(i, j int) bool { return history[i].Timestamp < history[j].Timestamp })
+	writeJSON(w, history)
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, r *http.Request) {
+	latest, err := s.latest()
+	if err != nil {
+		serverError(w, err)
+		return
+	}
+	s.Metrics.Observe(latest)
+	s.Metrics.Handler().ServeHTTP(w, r)
+}
+
+// latest returns the latest result of each endpoint and
+// location in s.Storage, sorted by title.
+func (s *Server) latest() ([]Result, error) {
+	index, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+	var latest []Result
+	seen := make(map[string]bool)
+	for _, name := range newestCheckFiles(index, maxMergeFiles) {
+		results, err := s.fetch(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, result := range results {
+			key := strings.ToLower(result.Title) + "\x00" + result.location()
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			latest = append(latest, result)
+		}
+	}
+	sort.SliceStable(latest, func(i, j int) bool { return latest[i].Title < latest[j].Title })
+	return latest, nil
+}
+
+// fetch returns the results in the check file name, from
+// the cache if possible.
+func (s *Server) fetch(name string) ([]Result, error) {
+	s.mu.Lock()
+	results, ok := s.cache[name]
+	s.mu.Unlock()
+	if ok {
+		return results, nil
+	}
+
+	results, err := s.Storage.Fetch(name)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	if s.cache == nil {
+		s.cache = make(map[string][]Result)
+	}
+	s.cache[name] = results
+	s.mu.Unlock()
+	return results, nil
+}
+
+// index returns the index of s.Storage, and removes the
+// check files that are no longer in it from the cache.
+func (s *Server) index() (map[string]int64, error) {
+	index, err := s.Storage.GetIndex()
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for name := range s.cache {
+		if _, ok := index[name]; !ok {
+			delete(s.cache, name)
+		}
+	}
+	return index, nil
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("serving JSON: %v", err)
+	}
+}
+
+func serverError(w http.ResponseWriter, err error) {
+	log.Printf("serving: %v", err)
+	http.Error(w, "internal server error", http.StatusInternalServerError)
+}
@@ -0,0 +1,95 @@
+package checkup
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestServer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	storage := FS{Dir: dir}
+
+	old := Timestamp() - int64(48*time.Hour)
+	if err := storage.Store([]Result{
+		{Title: "Web", Timestamp: old, Healthy: true},
+		{Title: "DB", Timestamp: old, Down: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if err := storage.Store([]Result{
+		{Title: "Web", Timestamp: Timestamp(), Degraded: true},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewServer(&Server{
+		Storage:    storage,
+		StatusPage: http.FS(fstest.MapFS{"index.html": {Data: []byte("status page")}}),
+	})
+	defer srv.Close()
+
+	get := func(path string, v interface{}) int {
+		resp, err := http.Get(srv.URL + path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if v != nil && resp.StatusCode == http.StatusOK {
+			if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+				t.Fatalf("%s: %v", path, err)
+			}
+		}
+		return resp.StatusCode
+	}
+
+	var index map[string]int64
+	if code := get("/check_files/index.json", &index); code != http.StatusOK || len(index) != 2 {
+		t.Fatalf("Expected an index of 2 check files, got %v (%d)", index, code)
+	}
+	for name := range index {
+		var results []Result
+		if code := get("/check_files/"+name, &results); code != http.StatusOK || len(results) == 0 {
+			t.Errorf("Expected results in %s, got %v (%d)", name, results, code)
+		}
+	}
+	if code := get("/check_files/..%2findex.json", nil); code != http.StatusNotFound {
+		t.Errorf("Expected files outside the index not to be found, got %d", code)
+	}
+
+	var report StatusReport
+	if code := get("/api/status", &report); code != http.StatusOK {
+		t.Fatalf("Expected status OK, got %d", code)
+	}
+	if got, want := report.Status, Down; got != want {
+		t.Errorf("Expected overall status %s, got %s", want, got)
+	}
+	if len(report.Endpoints) != 2 || report.Endpoints[1].Title != "Web" || report.Endpoints[1].Status != Degraded {
+		t.Errorf("Expected the latest status of each endpoint, got %+v", report.Endpoints)
+	}
+
+	var history []Result
+	if code := get("/api/endpoints/web/history", &history); code != http.StatusOK || len(history) != 1 {
+		t.Errorf("Expected 1 result in the default history, got %d (%d)", len(history), code)
+	}
+	if code := get("/api/endpoints/web/history?since=72h", &history); code != http.StatusOK || len(history) != 2 {
+		t.Errorf("Expected 2 results in the history since 72h, got %d (%d)", len(history), code)
+	}
+	if code := get("/api/endpoints/web/history?since=forever", nil); code != http.StatusBadRequest {
+		t.Errorf("Expected an invalid since to be a bad request, got %d", code)
+	}
+
+	if code := get("/", nil); code != http.StatusOK {
+		t.Errorf("Expected the status page, got %d", code)
+	}
+}
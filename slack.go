@@ -1,6 +1,7 @@
 package checkup
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"strings"
@@ -8,53 +9,83 @@ import (
 	slack "github.com/ashwanthkumar/slack-go-webhook"
 )
 
+func init() {
+	RegisterNotifier("slack", func() Notifier { return Slack{} })
+}
+
 // Slack consist of all the sub components required to use Slack API
 type Slack struct {
 	Name     string `json:"name"`
 	Username string `json:"username"`
 	Channel  string `json:"channel"`
 	Webhook  string `json:"webhook"`
+	NotifierOptions
 }
 
-var notifications map[string]int = make(map[string]int) // current notifications
-
-// Notify implements notifier interface
+// Notify implements Notifier, keeping the alerts of s in
+// memory.
 func (s Slack) Notify(results []Result) error {
-	for _, result := range results {
-		notificationStatus, hasNotification := notifications[result.Title]
-		if !result.Healthy {
-			notifications[result.Title] = 1
-			if !hasNotification || notificationStatus == 0 {
-				color := "danger"
-				if result.Degraded {
-					color = "warning"
-				}
-				s.Send(result, color)
-			}
-		} else if hasNotification && notificationStatus == 1 {
-			notifications[result.Title] = 0
-			s.Send(result, "good")
-		}
+	return s.NotifyState(results, defaultNotifierState)
+}
+
+// NotifyState implements StatefulNotifier. It alerts about
+// the endpoints that start failing or go from degraded to
+// down, reminds about them if s.RemindEvery is set, and
+// tells when they recover along with how long they were
+// failing.
+func (s Slack) NotifyState(results []Result, state NotifierState) error {
+	return s.notify(context.Background(), state, notifierKey("slack", s.Webhook, s.Channel), results, s.sendTransition)
+}
+
+// sendTransition sends a message about t, with the
+// templates of s.
+func (s Slack) sendTransition(t Transition) error {
+	title, body, err := s.render(t)
+	if err != nil {
+		return fmt.Errorf("slack: %v", err)
 	}
-	return nil
+	color := "good"
+	if !t.Recovered() {
+		color = statusColor(t.Alert.Status)
+	}
+	attach := slack.Attachment{Fallback: &title, Text: &body, Color: &color}
+	return s.post(t.Result, slack.Payload{
+		Text:        title,
+		Username:    s.Username,
+		Channel:     s.Channel,
+		Attachments: []slack.Attachment{attach},
+	})
+}
+
+// statusColor returns the color of the alerts about an
+// endpoint with the failing status.
+func statusColor(status StatusText) string {
+	if status == Down {
+		return "danger"
+	}
+	return "warning"
 }
 
+// Send sends a message about result to Slack, in color,
+// with its title, endpoint and status.
 func (s Slack) Send(result Result, color string) error {
 	attach := slack.Attachment{}
 	attach.AddField(slack.Field{Title: result.Title, Value: result.Endpoint})
 	attach.AddField(slack.Field{Title: "Status", Value: strings.ToUpper(fmt.Sprint(result.Status()))})
 	attach.Color = &color
-	payload := slack.Payload{
+	return s.post(result, slack.Payload{
 		Text:        result.Title,
 		Username:    s.Username,
 		Channel:     s.Channel,
 		Attachments: []slack.Attachment{attach},
-	}
+	})
+}
 
-	err := slack.Send(s.Webhook, "", payload)
-	if len(err) > 0 {
-		log.Printf("ERROR: %s", err)
-	}
+// post posts payload, about result, to the webhook of s.
+func (s Slack) post(result Result, payload slack.Payload) error {
 	log.Printf("Create request for %s", result.Endpoint)
+	if errs := slack.Send(s.Webhook, "", payload); len(errs) > 0 {
+		return fmt.Errorf("slack: %v", Errors(errs))
+	}
 	return nil
 }
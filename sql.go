@@ -1,9 +1,13 @@
 package checkup
 
 import (
+	dbsql "database/sql"
 	"encoding/json"
 	"errors"
+	"os"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jmoiron/sqlx"
@@ -11,6 +15,10 @@ import (
 	_ "github.com/mattn/go-sqlite3" // Enable sqlite3 backend
 )
 
+func init() {
+	RegisterStorage("sql", func() Storage { return SQL{} })
+}
+
 // schema is the table schema expected by the sqlite3 checkup storage.
 const schema = `
 CREATE TABLE checks (
@@ -44,24 +52,34 @@ type SQL struct {
 }
 
 func (sql SQL) dbConnect() (*sqlx.DB, error) {
+	driver, source, err := sql.dataSource()
+	if err != nil {
+		return nil, err
+	}
+	return sqlx.Connect(driver, source)
+}
+
+// dataSource returns the name of the driver and the data
+// source to connect to the database of sql.
+func (sql SQL) dataSource() (driver, source string, err error) {
 	// Only one SQL backend can be present
 	if sql.SqliteDBFile != "" && sql.PostgreSQL != nil {
-		return nil, errors.New("several SQL backends are configured")
+		return "", "", errors.New("several SQL backends are configured")
 	}
 
 	// SQLite3 configuration
 	if sql.SqliteDBFile != "" {
-		return sqlx.Connect("sqlite3", sql.SqliteDBFile)
+		return "sqlite3", sql.SqliteDBFile, nil
 	}
 
 	// PostgreSQL configuration
 	if sql.PostgreSQL != nil {
 		var pgOptions string
 		if sql.PostgreSQL.DBName == "" {
-			return nil, errors.New("missing PostgreSQL database name")
+			return "", "", errors.New("missing PostgreSQL database name")
 		}
 		if sql.PostgreSQL.User == "" {
-			return nil, errors.New("missing PostgreSQL username")
+			return "", "", errors.New("missing PostgreSQL username")
 		}
 		if sql.PostgreSQL.Host != "" {
 			pgOptions += " host=" + sql.PostgreSQL.Host
@@ -77,12 +95,12 @@ func (sql SQL) dbConnect() (*sqlx.DB, error) {
 		if sql.PostgreSQL.SSLMode != "" {
 			pgOptions += " sslmode=" + sql.PostgreSQL.SSLMode
 		}
-		return sqlx.Connect("postgres", pgOptions)
+		return "postgres", pgOptions, nil
 	}
 
 	// TODO: MySQL backend?
 
-	return nil, errors.New("no configured database backend")
+	return "", "", errors.New("no configured database backend")
 }
 
 // GetIndex returns the list of check results for the database.
@@ -138,13 +156,18 @@ func (sql SQL) Fetch(name string) ([]Result, error) {
 
 // Store stores results in the database.
 func (sql SQL) Store(results []Result) error {
+	return sql.StoreCheckFile(*GenerateFilename(), time.Now().UnixNano(), results)
+}
+
+// StoreCheckFile stores results in the database, as the
+// check file name with the given timestamp.
+func (sql SQL) StoreCheckFile(name string, timestamp int64, results []Result) error {
 	db, err := sql.dbConnect()
 	if err != nil {
 		return err
 	}
 	defer db.Close()
 
-	name := *GenerateFilename()
 	contents, err := json.Marshal(results)
 	if err != nil {
 		return err
@@ -152,7 +175,141 @@ func (sql SQL) Store(results []Result) error {
 
 	// Insert data
 	const insertResults = `INSERT INTO "checks" (name, timestamp, results) VALUES (?, ?, ?)`
-	_, err = db.Exec(insertResults, name, time.Now().UnixNano(), contents)
+	_, err = db.Exec(insertResults, name, timestamp, contents)
+	return err
+}
+
+// documentsSchema is the table schema of the documents,
+// which is created when needed.
+const documentsSchema = `CREATE TABLE IF NOT EXISTS documents (
+    name TEXT NOT NULL PRIMARY KEY,
+    contents TEXT
+)`
+
+// FetchDocument reads the document name from the database.
+func (sql SQL) FetchDocument(name string) ([]byte, error) {
+	db, err := sql.dbConnect()
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(documentsSchema); err != nil {
+		return nil, err
+	}
+	var contents []byte
+	err = db.Get(&contents, db.Rebind(`SELECT contents FROM documents WHERE name=?`), name)
+	if err == dbsql.ErrNoRows {
+		return nil, os.ErrNotExist
+	}
+	return contents, err
+}
+
+// StoreDocument stores the document name in the database.
+// It upserts with ON CONFLICT, which requires SQLite 3.24
+// or PostgreSQL 9.5, or later.
+func (sql SQL) StoreDocument(name string, contents []byte) error {
+	db, err := sql.dbConnect()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	if _, err := db.Exec(documentsSchema); err != nil {
+		return err
+	}
+	const upsert = `INSERT INTO documents (name, contents) VALUES (?, ?)
+		ON CONFLICT (name) DO UPDATE SET contents = excluded.contents`
+	_, err = db.Exec(db.Rebind(upsert), name, string(contents))
+	return err
+}
+
+// alertsSchema is the table schema of the alerts of
+// notifiers, which is created when needed.
+const alertsSchema = `CREATE TABLE IF NOT EXISTS notifier_alerts (
+    notifier TEXT NOT NULL,
+    endpoint TEXT NOT NULL,
+    alert TEXT,
+    PRIMARY KEY (notifier, endpoint)
+)`
+
+// alertsDBs are the connections to the databases of the
+// alerts of notifiers, by driver and data source. They are
+// kept open, as alerts are read for every endpoint on every
+// run.
+var (
+	alertsDBsMu sync.Mutex
+	alertsDBs   = make(map[string]*sqlx.DB)
+)
+
+// alertsDB returns the connection to the database of sql
+// for the alerts of notifiers, connecting and creating
+// their table on first use.
+func (sql SQL) alertsDB() (*sqlx.DB, error) {
+	driver, source, err := sql.dataSource()
+	if err != nil {
+		return nil, err
+	}
+	alertsDBsMu.Lock()
+	defer alertsDBsMu.Unlock()
+	key := driver + "\x00" + source
+	if db, ok := alertsDBs[key]; ok {
+		return db, nil
+	}
+	db, err := sqlx.Connect(driver, source)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := db.Exec(alertsSchema); err != nil {
+		db.Close()
+		return nil, err
+	}
+	alertsDBs[key] = db
+	return db, nil
+}
+
+// Alert reads the alert of notifier about endpoint from
+// the database. It implements NotifierState.
+func (sql SQL) Alert(notifier, endpoint string) (*Alert, error) {
+	db, err := sql.alertsDB()
+	if err != nil {
+		return nil, err
+	}
+	var contents []byte
+	err = db.Get(&contents, db.Rebind(`SELECT alert FROM notifier_alerts WHERE notifier=? AND endpoint=?`),
+		notifier, strings.ToLower(endpoint))
+	if err == dbsql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var alert Alert
+	if err := json.Unmarshal(contents, &alert); err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+// SetAlert stores the alert of notifier about endpoint in
+// the database, or deletes it if alert is nil. It
+// implements NotifierState.
+func (sql SQL) SetAlert(notifier, endpoint string, alert *Alert) error {
+	db, err := sql.alertsDB()
+	if err != nil {
+		return err
+	}
+	endpoint = strings.ToLower(endpoint)
+	if alert == nil {
+		_, err = db.Exec(db.Rebind(`DELETE FROM notifier_alerts WHERE notifier=? AND endpoint=?`), notifier, endpoint)
+		return err
+	}
+	contents, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	const upsert = `INSERT INTO notifier_alerts (notifier, endpoint, alert) VALUES (?, ?, ?)
+		ON CONFLICT (notifier, endpoint) DO UPDATE SET alert = excluded.alert`
+	_, err = db.Exec(db.Rebind(upsert), notifier, endpoint, string(contents))
 	return err
 }
 
@@ -0,0 +1,265 @@
+package checkup
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StateRules determine how the status reported for an
+// endpoint follows the status observed by its checks,
+// across runs. The zero value reports every observed
+// status as is.
+//
+// The rules of a checker override those of the Checkup,
+// except for those left at zero. A checker sets DownAfter
+// or UpAfter to 1 to report changes right away, and a
+// negative FlapThreshold to turn flap detection off.
+type StateRules struct {
+	// DownAfter is how many consecutive runs must fail
+	// (be degraded or down) before a healthy endpoint is
+	// reported as failing. Zero is the same as 1.
+	DownAfter int `json:"down_after,omitempty"`
+
+	// UpAfter is how many consecutive runs must pass
+	// before a failing endpoint is reported as healthy.
+	// Zero is the same as 1.
+	UpAfter int `json:"up_after,omitempty"`
+
+	// FlapThreshold is how many changes of the observed
+	// status within FlapWindow make an endpoint flapping.
+	// If zero or negative, flapping is not detected.
+	FlapThreshold int `json:"flap_threshold,omitempty"`
+
+	// FlapWindow is the period over which changes of the
+	// observed status are counted to detect flapping.
+	FlapWindow time.Duration `json:"flap_window,omitempty"`
+}
+
+// merge returns r with its unset (zero) rules taken from
+// defaults.
+func (r StateRules) merge(defaults StateRules) StateRules {
+	if r.DownAfter == 0 {
+		r.DownAfter = defaults.DownAfter
+	}
+	if r.UpAfter == 0 {
+		r.UpAfter = defaults.UpAfter
+	}
+	if r.FlapThreshold == 0 {
+		r.FlapThreshold = defaults.FlapThreshold
+	}
+	if r.FlapWindow == 0 {
+		r.FlapWindow = defaults.FlapWindow
+	}
+	return r
+}
+
+// enabled returns whether r needs state to be kept.
+func (r StateRules) enabled() bool {
+	return r.DownAfter > 1 || r.UpAfter > 1 || r.FlapThreshold > 0
+}
+
+// EndpointState is the state of an endpoint across runs.
+// It is stored with the results it was evaluated for.
+type EndpointState struct {
+	// Status is the status being reported for the endpoint:
+	// healthy, degraded or down.
+	Status StatusText `json:"status"`
+
+	// Observed is the status observed by the latest check.
+	Observed StatusText `json:"observed"`
+
+	// Failures and Passes count the consecutive runs that
+	// failed and passed, respectively, up to the latest.
+	Failures int `json:"failures,omitempty"`
+	Passes   int `json:"passes,omitempty"`
+
+	// Changes are the timestamps (UTC UnixNano) of the
+	// changes of the observed status within the flap window.
+	Changes []int64 `json:"changes,omitempty"`
+
+	// Flapping is whether the observed status changed too
+	// often within the flap window.
+	Flapping bool `json:"flapping,omitempty"`
+}
+
+// evaluate updates s with result and rules, and returns
+// result with its conclusion replaced by the status s
+// reports.
+func (s *EndpointState) evaluate(result Result, rules StateRules) Result {
+	observed := result.Status()
+	if observed == Unknown {
+		return result
+	}
+
+	if s.Status == "" {
+		s.Status = Healthy
+	} else if observed != s.Observed {
+		s.Changes = append(s.Changes, result.Timestamp)
+	}
+	s.Observed = observed
+	if observed == Healthy {
+		s.Passes++
+		s.Failures = 0
+	} else {
+		s.Failures++
+		s.Passes = 0
+	}
+
+	cutoff := result.Timestamp - int64(rules.FlapWindow)
+	for len(s.Changes) > 0 && (rules.FlapThreshold <= 0 || s.Changes[0] <= cutoff) {
+		s.Changes = s.Changes[1:]
+	}
+	s.Flapping = rules.FlapThreshold > 0 && len(s.Changes) >= rules.FlapThreshold
+
+	switch {
+	case observed == s.Status:
+	case observed == Healthy:
+		if s.Passes >= rules.UpAfter {
+			s.Status = Healthy
+		} else {
+			result.Notice = fmt.Sprintf("recovering: passed %d of %d consecutive runs", s.Passes, rules.UpAfter)
+		}
+	case s.Status == Healthy:
+		if s.Failures >= rules.DownAfter {
+			s.Status = observed
+		} else {
+			result.Notice = fmt.Sprintf("%s in %d of %d consecutive runs", observed, s.Failures, rules.DownAfter)
+		}
+	default:
+		// already failing; degraded and down follow each other
+		s.Status = observed
+	}
+
+	result.Healthy = s.Status == Healthy
+	result.Degraded = s.Status == Degraded
+	result.Down = s.Status == Down
+	result.Flapping = s.Flapping
+	state := *s
+	state.Changes = append([]int64(nil), s.Changes...)
+	result.State = &state
+	return result
+}
+
+// maxStateFiles is how many of the latest check files are
+// read, at most, to restore the state of the endpoints
+// from storage.
+const maxStateFiles = 100
+
+// states holds the state of endpoints between runs of a
+// Checkup, keyed by result title.
+type states struct {
+	sync.Mutex
+	loaded bool
+	m      map[string]EndpointState
+}
+
+func newStates() *states {
+	return &states{m: make(map[string]EndpointState)}
+}
+
+// load restores the state of the endpoints from c.StateFile,
+// or else from the latest check files in c.Storage if it is
+// a StorageReader. It only does so once.
+func (s *states) load(c Checkup) error {
+	if s.loaded {
+		return nil
+	}
+	if c.StateFile != "" {
+		b, err := ioutil.ReadFile(c.StateFile)
+		if os.IsNotExist(err) {
+			s.loaded = true
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(b, &s.m); err != nil {
+			return fmt.Errorf("reading state file: %v", err)
+		}
+		s.loaded = true
+		return nil
+	}
+
+	if reader, ok := c.Storage.(StorageReader); ok {
+		names, err := latestCheckFiles(reader, maxStateFiles)
+		if err != nil {
+			return fmt.Errorf("restoring state: %v", err)
+		}
+
+		// the latest state of each endpoint wins
+		latest := make(map[string]int64)
+		for _, name := range names {
+			results, err := reader.Fetch(name)
+			if err != nil {
+				return fmt.Errorf("restoring state: %v", err)
+			}
+			for _, result := range results {
+				if result.State == nil {
+					continue
+				}
+				key := strings.ToLower(result.Title)
+				if ts, ok := latest[key]; ok && ts >= result.Timestamp {
+					continue
+				}
+				latest[key] = result.Timestamp
+				s.m[key] = *result.State
+			}
+		}
+	}
+	s.loaded = true
+	return nil
+}
+
+// save writes the state of the endpoints to c.StateFile,
+// if set. Otherwise, the state is saved with the results.
+func (s *states) save(c Checkup) error {
+	if c.StateFile == "" {
+		return nil
+	}
+	b, err := json.Marshal(s.m)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.StateFile, b, 0600)
+}
+
+// evaluateStates applies the state rules of c and of its
+// checkers to results, using and updating the state kept
+// across runs. Results during maintenance, and the runs of
+// a stateless c, leave the state untouched.
+func (c Checkup) evaluateStates(results []Result) error {
+	if c.stateless {
+		return nil
+	}
+	var evaluated bool
+	s := c.states
+	if s == nil {
+		s = newStates()
+	}
+	s.Lock()
+	defer s.Unlock()
+
+	for i, checker := range c.Checkers {
+		rules := optionsOf(checker).StateRules.merge(c.StateRules)
+		if !rules.enabled() || results[i].Maintenance {
+			continue
+		}
+		if err := s.load(c); err != nil {
+			return err
+		}
+		key := strings.ToLower(results[i].Title)
+		state := s.m[key]
+		results[i] = state.evaluate(results[i], rules)
+		s.m[key] = state
+		evaluated = true
+	}
+
+	if !evaluated {
+		return nil
+	}
+	return s.save(c)
+}
@@ -0,0 +1,158 @@
+package checkup
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestEndpointStateDebounce(t *testing.T) {
+	rules := StateRules{DownAfter: 3, UpAfter: 2}
+	var s EndpointState
+	for i, test := range []struct {
+		observed Result
+		status   StatusText
+	}{
+		{Result{Healthy: true}, Healthy},
+		{Result{Down: true}, Healthy},
+		{Result{Degraded: true}, Healthy},
+		{Result{Down: true}, Down},
+		{Result{Degraded: true}, Degraded},
+		{Result{Healthy: true}, Degraded},
+		{Result{Down: true}, Down},
+		{Result{Healthy: true}, Down},
+		{Result{Healthy: true}, Healthy},
+		{Result{Down: true}, Healthy},
+	} {
+		test.observed.Timestamp = int64(i)
+		result := s.evaluate(test.observed, rules)
+		if got := result.Status(); got != test.status {
+			t.Errorf("Test %d: Expected status %s, got %s", i, test.status, got)
+		}
+		if result.State == nil || result.State.Observed != test.observed.Status() {
+			t.Errorf("Test %d: Expected the state to be attached to the result, got %+v", i, result.State)
+		}
+	}
+	if got, want := s.Failures, 1; got != want {
+		t.Errorf("Expected %d consecutive failures, got %d", want, got)
+	}
+
+	result := s.evaluate(Result{Down: true, Timestamp: 10}, rules)
+	if result.Notice != "down in 2 of 3 consecutive runs" {
+		t.Errorf("Expected a notice about the pending failure, got %q", result.Notice)
+	}
+}
+
+func TestEndpointStateFlapping(t *testing.T) {
+	rules := StateRules{FlapThreshold: 3, FlapWindow: 10}
+	var s EndpointState
+	for i, test := range []struct {
+		observed Result
+		flapping bool
+	}{
+		{Result{Healthy: true, Timestamp: 0}, false},
+		{Result{Down: true, Timestamp: 1}, false},
+		{Result{Healthy: true, Timestamp: 2}, false},
+		{Result{Down: true, Timestamp: 3}, true},
+		{Result{Down: true, Timestamp: 4}, true},
+		{Result{Down: true, Timestamp: 12}, false},
+	} {
+		result := s.evaluate(test.observed, rules)
+		if result.Flapping != test.flapping {
+			t.Errorf("Test %d: Expected Flapping=%v, got %v", i, test.flapping, result.Flapping)
+		}
+		if test.flapping && result.Status() != Flapping {
+			t.Errorf("Test %d: Expected status %s, got %s", i, Flapping, result.Status())
+		}
+	}
+}
+
+func TestCheckStateFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	down := &sequence{Result{Title: "Example", Down: true}}
+	c := Checkup{
+		Checkers:   []Checker{down},
+		StateRules: StateRules{DownAfter: 2},
+		StateFile:  filepath.Join(dir, "state.json"),
+	}
+
+	// every run starts with a fresh Checkup, as in separate processes
+	for i, want := range []StatusText{Healthy, Down, Down} {
+		results, err := c.Check()
+		if err != nil {
+			t.Fatalf("Run %d: Didn't expect an error: %v", i, err)
+		}
+		if got := results[0].Status(); got != want {
+			t.Errorf("Run %d: Expected status %s, got %s", i, want, got)
+		}
+	}
+}
+
+func TestCheckStateFromStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkup")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	c := Checkup{
+		Checkers:   []Checker{&sequence{Result{Title: "Example", Down: true}}},
+		Storage:    FS{Dir: dir},
+		StateRules: StateRules{DownAfter: 2},
+	}
+
+	for i, want := range []StatusText{Healthy, Down} {
+		results, err := c.Check()
+		if err != nil {
+			t.Fatalf("Run %d: Didn't expect an error: %v", i, err)
+		}
+		if got := results[0].Status(); got != want {
+			t.Errorf("Run %d: Expected status %s, got %s", i, want, got)
+		}
+		if err := c.Storage.Store(results); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond) // distinct check file names
+	}
+}
+
+func TestStateRulesMerge(t *testing.T) {
+	defaults := StateRules{DownAfter: 3, UpAfter: 2, FlapThreshold: 5, FlapWindow: time.Hour}
+
+	if got := (StateRules{}).merge(defaults); got != defaults {
+		t.Errorf("Expected the defaults, got %+v", got)
+	}
+	got := StateRules{DownAfter: 1, UpAfter: 1, FlapThreshold: -1}.merge(defaults)
+	if got.enabled() {
+		t.Errorf("Expected the rules to be turned off, got %+v", got)
+	}
+
+	var s EndpointState
+	for i := 0; i < 10; i++ {
+		down := i%2 == 0
+		result := s.evaluate(Result{Title: "Example", Timestamp: int64(i), Down: down, Healthy: !down}, got)
+		if result.Flapping || result.Down != down {
+			t.Fatalf("Run %d: expected the observed status to be reported, got %s", i, result.Status())
+		}
+	}
+}
+
+// sequence is a Checker that returns its results in
+// turn, repeating the last one.
+type sequence []Result
+
+func (s *sequence) Check() (Result, error) {
+	result := (*s)[0]
+	if len(*s) > 1 {
+		*s = (*s)[1:]
+	}
+	result.Timestamp = Timestamp()
+	return result, nil
+}
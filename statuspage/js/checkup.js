@@ -96,6 +96,21 @@ checkup.makeTimeTag = function(ms) {
 			+ '</time>';
 }
 
+// escapeHTML escapes str to be inserted as HTML.
+checkup.escapeHTML = function(str) {
+	return String(str)
+		.replace(/&/g, "&amp;")
+		.replace(/</g, "&lt;")
+		.replace(/>/g, "&gt;")
+		.replace(/"/g, "&quot;");
+};
+
+// Maps incident statuses to their associated color class.
+checkup.incidentColor = {investigating: "red", identified: "yellow", monitoring: "blue", resolved: "green"};
+
+// How many resolved incidents to show on the status page.
+checkup.pastIncidents = 10;
+
 // All check files must have this suffix.
 checkup.checkFileSuffix = "-check.json";
 
@@ -113,12 +128,12 @@ checkup.unixNanoToD3Timestamp = function(unixNanoTimestamp) {
 };
 
 // Maps status names to their associated color class.
-checkup.color = {healthy: "green", degraded: "yellow", down: "red"};
+checkup.color = {healthy: "green", degraded: "yellow", flapping: "yellow", down: "red", maintenance: "blue"};
 
 // Stores the checks that are downloaded (1:1 ratio with check files)
 checkup.checks = [];
 
-// Stores all the results, keyed by endpoint
+// Stores all the results, keyed by endpoint (see resultKey)
 checkup.results = {};
 
 // Stores all the results, keyed by timestamp indicated in the JSON
@@ -129,7 +144,7 @@ checkup.groupedResults = {};
 // guaranteed until all results are loaded
 checkup.orderedResults = [];
 
-// Stores the charts (keyed by endpoint) and all their data/info/elements
+// Stores the charts (keyed by endpoint, see resultKey) and all their data/info/elements
 checkup.charts = {};
 
 // ID counter for the charts, always incremented
@@ -155,6 +170,21 @@ checkup.lastResultTs = null;
 // check file name).
 checkup.lastCheckTs = null;
 
+// Returns the key under which result is stored and charted:
+// its endpoint, followed by its location for the results of
+// each location when checks are run from several locations.
+checkup.resultKey = function(result) {
+	var loc = result.location || result.node;
+	return loc ? result.endpoint+"@"+loc : result.endpoint;
+};
+
+// Returns the title to display for result, which includes
+// its location if any.
+checkup.resultTitle = function(result) {
+	var loc = result.location || result.node;
+	return loc ? result.title+" ("+loc+")" : result.title;
+};
+
 checkup.makeChart = function(title) {
 	var chart = {
 		id: "chart"+(checkup.chartCounter++),
@@ -218,6 +248,8 @@ checkup.computeStats = function(result) {
 		else
 			return values[half].rtt;
 	}
+	if (!result.times || result.times.length == 0)
+		return {total: 0, average: 0, median: 0, min: 0, max: 0};
 	var sum = 0, min, max;
 	for (var i = 0; i < result.times.length; i++) {
 		var attempt = result.times[i];
@@ -18,6 +18,7 @@ checkup.config = {
   "status_text": {
     "healthy": "Situation Normal",
     "degraded": "Degraded Service",
-    "down": "Service Disruption"
+    "down": "Service Disruption",
+    "maintenance": "Planned Maintenance"
   }
 };
@@ -54,6 +54,38 @@ checkup.storage = (function() {
 		});
 	};
 
+	// getIncidents gets the incidents, newest first, and
+	// executes callback with them. Storage without incidents
+	// yields none.
+	this.getIncidents = function(callback) {
+		getDocument('incidents.json', callback);
+	};
+
+	// getMaintenance gets the scheduled maintenance, by start
+	// time, and executes callback with it. Storage without
+	// scheduled maintenance yields none.
+	this.getMaintenance = function(callback) {
+		getDocument('maintenance.json', callback);
+	};
+
+	// getDocument gets the JSON array stored in the document
+	// name next to the check files, and executes callback
+	// with it, or with an empty array if there is none.
+	function getDocument(name, callback) {
+		var request = new XMLHttpRequest();
+		request.open('GET', url+'/'+name, true);
+		request.onload = function() {
+			if (request.status >= 200 && request.status < 400)
+				callback(JSON.parse(request.responseText) || []);
+			else
+				callback([]);
+		};
+		request.onerror = function() {
+			callback([]);
+		};
+		request.send();
+	};
+
 	// getNewChecks gets any checks since the timestamp on the file name
 	// of the youngest check file that has been downloaded. If no check
 	// files have been downloaded, no new check files will be loaded.
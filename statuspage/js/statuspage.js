@@ -23,6 +23,12 @@ document.addEventListener('DOMContentLoaded', function() {
 	checkup.dom.checkcount = document.getElementById("info-checkcount");
 	checkup.dom.lastcheck = document.getElementById("info-lastcheck");
 	checkup.dom.timeline = document.getElementById("timeline");
+	checkup.dom.activeIncidents = document.getElementById("active-incidents");
+	checkup.dom.pastIncidents = document.getElementById("past-incidents");
+	checkup.dom.pastIncidentsList = document.getElementById("past-incidents-list");
+	checkup.dom.scheduledMaintenance = document.getElementById("scheduled-maintenance");
+	checkup.storage.getIncidents(renderIncidents);
+	checkup.storage.getMaintenance(renderMaintenance);
 	// Immediately begin downloading check files, and keep page updated
 	checkup.storage.getChecksWithin(checkup.config.timeframe, processNewCheckFile, allCheckFilesLoaded);
 
@@ -31,6 +37,8 @@ document.addEventListener('DOMContentLoaded', function() {
 
 setInterval(function() {
 	checkup.storage.getNewChecks(processNewCheckFile, allCheckFilesLoaded);
+	checkup.storage.getIncidents(renderIncidents);
+	checkup.storage.getMaintenance(renderMaintenance);
 }, checkup.config.refresh_interval * 1000);
 // Run immediately so as not to wait for interval
 checkup.storage.getNewChecks(processNewCheckFile, allCheckFilesLoaded)
@@ -66,12 +74,13 @@ function processNewCheckFile(json, filename) {
 		else
 			checkup.groupedResults[result.timestamp].push(result);
 
-		if (!checkup.results[result.endpoint])
-			checkup.results[result.endpoint] = [result];
+		var key = checkup.resultKey(result);
+		if (!checkup.results[key])
+			checkup.results[key] = [result];
 		else
-			checkup.results[result.endpoint].push(result);
+			checkup.results[key].push(result);
 
-		var chart = checkup.charts[result.endpoint] || checkup.makeChart(result.title);
+		var chart = checkup.charts[key] || checkup.makeChart(checkup.resultTitle(result));
 		chart.results.push(result);
 
 		var ts = checkup.unixNanoToD3Timestamp(result.timestamp);
@@ -94,8 +103,8 @@ function processNewCheckFile(json, filename) {
 		result.stats = checkup.computeStats(result);
 
 		var chart = process(result);
-		checkup.charts[result.endpoint] = chart;
-		checkup.charts[result.endpoint].endpoint = result.endpoint;
+		checkup.charts[checkup.resultKey(result)] = chart;
+		chart.endpoint = result.endpoint;
 	});
 
 	var byTimestamp = function(a, b) {
@@ -130,8 +139,8 @@ function allCheckFilesLoaded(numChecksLoaded, numResultsLoaded) {
 	// First load the last known status of each endpoint
 	for (var i = checkup.events.length-1; i >= 0; i--) {
 		var result = checkup.events[i].result;
-		if (!statuses[result.endpoint])
-			statuses[result.endpoint] = checkup.events[i].status;
+		if (!statuses[checkup.resultKey(result)])
+			statuses[checkup.resultKey(result)] = checkup.events[i].status;
 	}
 
 	// Then go through the new results and look for new events
@@ -139,10 +148,12 @@ function allCheckFilesLoaded(numChecksLoaded, numResultsLoaded) {
 		var result = checkup.orderedResults[i];
 
 		var status = "healthy";
-		if (result.degraded) status = "degraded";
+		if (result.maintenance) status = "maintenance";
+		else if (result.flapping) status = "flapping";
+		else if (result.degraded) status = "degraded";
 		else if (result.down) status = "down";
 
-		if (status != statuses[result.endpoint]) {
+		if (status != statuses[checkup.resultKey(result)]) {
 			// New event because status changed
 			newEvents.push({
 				id: checkup.eventCounter++,
@@ -160,7 +171,7 @@ function allCheckFilesLoaded(numChecksLoaded, numResultsLoaded) {
 			});
 		}
 
-		statuses[result.endpoint] = status;
+		statuses[checkup.resultKey(result)] = status;
 	}
 
 	checkup.events = checkup.events.concat(newEvents);
@@ -183,8 +194,8 @@ function allCheckFilesLoaded(numChecksLoaded, numResultsLoaded) {
 		// Save this event to the chart's event series so it will render on the graph
 		var imgFile = "ok.png", imgWidth = 15, imgHeight = 15; // the different icons look smaller/larger because of their shape
 		if (e.status == "down") { imgFile = "incident.png"; imgWidth = 20; imgHeight = 20; }
-		else if (e.status == "degraded") { imgFile = "degraded.png"; imgWidth = 25; imgHeight = 25; }
-		var chart = checkup.charts[e.result.endpoint];
+		else if (e.status == "degraded" || e.status == "flapping") { imgFile = "degraded.png"; imgWidth = 25; imgHeight = 25; }
+		var chart = checkup.charts[checkup.resultKey(e.result)];
 		chart.series.events.push({
 			timestamp: checkup.unixNanoToD3Timestamp(e.result.timestamp),
 			rtt: e.result.stats.median,
@@ -206,7 +217,20 @@ function allCheckFilesLoaded(numChecksLoaded, numResultsLoaded) {
 			evtElem.innerHTML += '<div class="message-body">'+e.message+'</div>';
 		} else {
 			evtElem.classList.add("event");
-			evtElem.innerHTML = '<span class="time">'+renderTime(e.result.timestamp)+'</span> '+e.result.title+" "+e.status;
+			evtElem.innerHTML = '<span class="time">'+renderTime(e.result.timestamp)+'</span> '+checkup.resultTitle(e.result)+" "+e.status;
+			if (e.result.locations) {
+				var locs = [];
+				for (var loc in e.result.locations)
+					locs.push(loc+": "+e.result.locations[loc]);
+				evtElem.innerHTML += ' <span class="locations">('+locs.join(", ")+')</span>';
+			}
+		}
+		if (e.result.details) {
+			// show what the checker found when hovering the event
+			var details = [];
+			for (var key in e.result.details)
+				details.push(key+": "+e.result.details[key]);
+			evtElem.title = details.join("\n");
 		}
 		checkup.dom.timeline.insertBefore(evtElem, checkup.dom.timeline.childNodes[0]);
 	}
@@ -218,10 +242,17 @@ function allCheckFilesLoaded(numChecksLoaded, numResultsLoaded) {
 	for (var endpoint in checkup.results) {
 		if (overall == "down") break;
 		var lastResult = checkup.results[endpoint][checkup.results[endpoint].length-1];
+		// the results of a location don't count when they are
+		// merged (see checkup merge)
+		if (lastResult && (lastResult.location || lastResult.node) && checkup.results[lastResult.endpoint])
+			continue;
 		if (lastResult) {
-			if (lastResult.down)
+			if (lastResult.maintenance) {
+				if (overall == "healthy")
+					overall = "maintenance";
+			} else if (lastResult.down)
 				overall = "down";
-			else if (lastResult.degraded)
+			else if (lastResult.degraded || lastResult.flapping)
 				overall = "degraded";
 		}
 	}
@@ -234,6 +265,10 @@ function allCheckFilesLoaded(numChecksLoaded, numResultsLoaded) {
 		checkup.dom.favicon.href = "images/status-yellow.png";
 		checkup.dom.status.className = "yellow";
 		checkup.dom.statustext.innerHTML = checkup.config.status_text.degraded || "Sub-Optimal";
+	} else if (overall == "maintenance") {
+		checkup.dom.favicon.href = "images/status-gray.png";
+		checkup.dom.status.className = "blue";
+		checkup.dom.statustext.innerHTML = checkup.config.status_text.maintenance || "Planned Maintenance";
 	} else if (overall == "down") {
 		checkup.dom.favicon.href = "images/status-red.png";
 		checkup.dom.status.className = "red";
@@ -500,6 +535,64 @@ function renderChart(chart) {
 }
 
 
+// renderIncidents shows the active incidents with all their
+// updates, and the latest resolved incidents.
+function renderIncidents(incidents) {
+	var active = "", past = "", pastCount = 0;
+	for (var i = 0; i < incidents.length; i++) {
+		var incident = incidents[i];
+		if (incident.status == "resolved") {
+			if (pastCount++ >= checkup.pastIncidents) continue;
+			past += renderIncident(incident, false);
+		} else {
+			active += renderIncident(incident, true);
+		}
+	}
+	checkup.dom.activeIncidents.innerHTML = active;
+	checkup.dom.pastIncidentsList.innerHTML = past;
+	checkup.dom.pastIncidents.style.display = pastCount > 0 ? '' : 'none';
+}
+
+// renderIncident returns the HTML of incident, with all
+// its updates if full, or with its last update only.
+function renderIncident(incident, full) {
+	var color = checkup.incidentColor[incident.status] || "gray";
+	var html = '<div class="incident '+color+'">';
+	html += '<div class="incident-head">'+checkup.escapeHTML(incident.title)
+		+ ' <span class="incident-status">'+checkup.escapeHTML(incident.status)+'</span></div>';
+	if (incident.endpoints && incident.endpoints.length)
+		html += '<div class="incident-endpoints">Affects '+checkup.escapeHTML(incident.endpoints.join(", "))+'</div>';
+	var updates = incident.updates || [];
+	if (!full) updates = updates.slice(-1);
+	for (var i = updates.length-1; i >= 0; i--) {
+		var u = updates[i];
+		html += '<div class="incident-update"><b>'+checkup.escapeHTML(u.status)+'</b> &mdash; '
+			+ checkup.escapeHTML(u.message)
+			+ ' <span class="incident-time">'+new Date(u.timestamp * 1e-6).toLocaleString()+'</span></div>';
+	}
+	return html+'</div>';
+}
+
+// renderMaintenance shows the scheduled maintenance that
+// is ongoing or upcoming.
+function renderMaintenance(scheduled) {
+	var html = "", now = new Date();
+	for (var i = 0; i < scheduled.length; i++) {
+		var m = scheduled[i];
+		var start = new Date(m.start), end = new Date(m.end);
+		if (end <= now) continue;
+		html += '<div class="incident blue">';
+		html += '<div class="incident-head">'+(start <= now ? 'Maintenance in progress' : 'Scheduled maintenance')
+			+ ' <span class="incident-status">'+start.toLocaleString()+' &ndash; '+end.toLocaleString()+'</span></div>';
+		if (m.endpoints && m.endpoints.length)
+			html += '<div class="incident-endpoints">Affects '+checkup.escapeHTML(m.endpoints.join(", "))+'</div>';
+		if (m.message)
+			html += '<div class="incident-update">'+checkup.escapeHTML(m.message)+'</div>';
+		html += '</div>';
+	}
+	checkup.dom.scheduledMaintenance.innerHTML = html;
+}
+
 function highlightSameEvent() {
 	var elems = document.querySelectorAll(".event-item:not(.event-id-"+this.getAttribute("data-eventid")+")");
 	for (var i = 0; i < elems.length; i++) {
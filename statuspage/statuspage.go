@@ -0,0 +1,11 @@
+// Package statuspage holds the assets of the checkup status
+// page, so that they can be served by checkup itself.
+package statuspage
+
+import "embed"
+
+// Assets are the files of the status page: index.html, and
+// the css, images and js directories.
+//
+//go:embed index.html css images js
+var Assets embed.FS
@@ -1,15 +1,19 @@
 package checkup
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
-	"fmt"
 	"io/ioutil"
 	"net"
 	"time"
 )
 
+func init() {
+	RegisterChecker("tcp", func() Checker { return TCPChecker{} })
+}
+
 // TCPChecker implements a Checker for TCP endpoints.
 type TCPChecker struct {
 	// Name is the name of the endpoint.
@@ -54,70 +58,98 @@ type TCPChecker struct {
 	// quickly in succession. By default, no waiting
 	// occurs between retries.
 	RetrySpacing time.Duration `json:"retry_spacing,omitempty"`
+
+	// CheckerOptions are the settings common to all checkers.
+	CheckerOptions
+}
+
+// Title returns the endpoint name of c.
+func (c TCPChecker) Title() string {
+	return c.Name
 }
 
 // Check performs checks using c according to its configuration.
 // An error is only returned if there is a configuration error.
 func (c TCPChecker) Check() (Result, error) {
+	return c.CheckContext(context.Background())
+}
+
+// CheckContext performs checks like Check, aborting pending
+// connections and attempts once ctx is done.
+func (c TCPChecker) CheckContext(ctx context.Context) (Result, error) {
 	if c.Attempts < 1 {
 		c.Attempts = 1
 	}
 	if c.Retries < 1 {
 		c.Retries = 0
 	}
+	if err := c.validate(); err != nil {
+		return Result{}, err
+	}
 
 	result := Result{Title: c.Name, Endpoint: c.URL, Timestamp: Timestamp()}
-	result.Times = c.doChecks()
+	result.Times, result.Details = c.doChecks(ctx)
 
 	return c.conclude(result), nil
 }
 
-// doChecks executes and returns each attempt.
-func (c TCPChecker) doChecks() Attempts {
+// doChecks executes and returns each attempt, along with
+// the details of the last connection established.
+func (c TCPChecker) doChecks(ctx context.Context) (Attempts, map[string]interface{}) {
 	timeout := c.Timeout
 	if timeout == 0 {
 		timeout = 1 * time.Second
 	}
 
 	checks := make(Attempts, c.Attempts)
+	details := make(map[string]interface{})
 	for i := 0; i < c.Attempts; i++ {
+		if err := ctx.Err(); err != nil {
+			checks[i].Error = attemptError(ctx, err)
+			continue
+		}
 		start := time.Now()
-		err := c.doCheck(timeout)
+		err := c.doCheck(ctx, timeout, details)
 		if err != nil {
 			// retries
 			if c.Retries > 0 {
-				err = c.doRetries(timeout)
+				err = c.doRetries(ctx, timeout, details)
 				if err != nil {
-					checks[i].Error = err.Error()
+					checks[i].Error = attemptError(ctx, err)
 				} else {
 					checks[i].RTT = time.Since(start)
 				}
 			} else {
-				checks[i].Error = err.Error()
+				checks[i].Error = attemptError(ctx, err)
 			}
 		} else {
 			checks[i].RTT = time.Since(start)
 		}
 	}
-	return checks
+	if len(details) == 0 {
+		details = nil
+	}
+	return checks, details
 }
 
 // doRetries executes retries and returns last error.
-func (c TCPChecker) doRetries(timeout time.Duration) error {
+func (c TCPChecker) doRetries(ctx context.Context, timeout time.Duration, details map[string]interface{}) error {
 	j := 1
 	for {
 		if c.RetrySpacing > 0 {
-			time.Sleep(c.RetrySpacing)
+			sleep(ctx, c.RetrySpacing)
 		}
-		err := c.doCheck(timeout)
-		if j >= c.Retries || err == nil {
+		err := c.doCheck(ctx, timeout, details)
+		if j >= c.Retries || err == nil || ctx.Err() != nil {
 			return err
 		}
 		j++
 	}
 }
 
-func (c TCPChecker) doCheck(timeout time.Duration) error {
+// doCheck connects to the endpoint and returns error. The
+// details of the connection are recorded in details.
+func (c TCPChecker) doCheck(ctx context.Context, timeout time.Duration, details map[string]interface{}) error {
 	var err error
 	var conn net.Conn
 	if c.TLSEnabled {
@@ -140,11 +172,16 @@ func (c TCPChecker) doCheck(timeout time.Duration) error {
 			}
 			tlsConfig.RootCAs = pool
 		}
-		if conn, err = tls.DialWithDialer(dialer, "tcp", c.URL, &tlsConfig); err == nil {
-			conn.Close()
+		var tlsConn *tls.Conn
+		if tlsConn, err = dialTLSContext(ctx, dialer, c.URL, &tlsConfig); err == nil {
+			details["remote_addr"] = tlsConn.RemoteAddr().String()
+			certificateDetails(details, "tls_", tlsConn.ConnectionState())
+			tlsConn.Close()
 		}
 	} else {
-		if conn, err = net.DialTimeout("tcp", c.URL, c.Timeout); err == nil {
+		dialer := &net.Dialer{Timeout: c.Timeout}
+		if conn, err = dialer.DialContext(ctx, "tcp", c.URL); err == nil {
+			details["remote_addr"] = conn.RemoteAddr().String()
 			conn.Close()
 		}
 	}
@@ -158,22 +195,19 @@ func (c TCPChecker) doCheck(timeout time.Duration) error {
 func (c TCPChecker) conclude(result Result) Result {
 	result.ThresholdRTT = c.ThresholdRTT
 
+	stats := result.ComputeStats()
+
 	// Check errors (down)
-	for i := range result.Times {
-		if result.Times[i].Error != "" {
-			result.Down = true
-			return result
-		}
+	if c.down(stats) {
+		result.Down = true
+		return result
 	}
 
 	// Check round trip time (degraded)
-	if c.ThresholdRTT > 0 {
-		stats := result.ComputeStats()
-		if stats.Median > c.ThresholdRTT {
-			result.Notice = fmt.Sprintf("median round trip time exceeded threshold (%s)", c.ThresholdRTT)
-			result.Degraded = true
-			return result
-		}
+	if degraded, notice := c.degraded(stats, c.ThresholdRTT); degraded {
+		result.Notice = notice
+		result.Degraded = true
+		return result
 	}
 
 	result.Healthy = true
@@ -42,6 +42,9 @@ func TestTCPChecker(t *testing.T) {
 	if got, want := result.Endpoint, endpt; got != want {
 		t.Errorf("Expected result.Endpoint='%s', got '%s'", want, got)
 	}
+	if got := result.Details["remote_addr"]; got == nil {
+		t.Error("Expected remote_addr detail, got none")
+	}
 	if got, want := result.Down, false; got != want {
 		t.Errorf("Expected result.Down=%v, got %v", want, got)
 	}
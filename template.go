@@ -0,0 +1,163 @@
+package checkup
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultTitleTemplate is the default template of the title
+// of the messages of notifiers. See NotifierOptions.
+const DefaultTitleTemplate = `{{.Result.Title}} ` +
+	`{{if eq .Kind "recovered"}}recovered` +
+	`{{else if eq .Kind "worsened"}}went from degraded to down` +
+	`{{else if eq .Kind "reminder"}}is still {{.Status}}` +
+	`{{else}}is {{.Status}}{{end}}`
+
+// DefaultBodyTemplate is the default template of the body
+// of the messages of notifiers. See NotifierOptions.
+const DefaultBodyTemplate = `{{or .Result.Endpoint .Result.Title}} is {{.Status}}{{with .Notice}}: {{.}}{{end}}.
+{{- if eq .Kind "recovered"}}
+It was failing for {{.Duration}}, since {{.Since.Format "Mon, 02 Jan 2006 15:04:05 MST"}}.
+{{- else if ne .Kind "failing"}}
+It has been failing for {{.Duration}}, since {{.Since.Format "Mon, 02 Jan 2006 15:04:05 MST"}}.
+{{- end}}
+{{- with .Message}}
+{{.}}{{end}}
+{{- with .Node}}
+Checked by {{.}}.{{end}}`
+
+// Message is what the templates of the messages of
+// notifiers are executed with: a transition of an endpoint
+// and what is known about it.
+type Message struct {
+	Kind   TransitionKind
+	Result Result
+
+	// Status is the status of Result, and Previous is the
+	// status notified before it, or healthy.
+	Status   StatusText
+	Previous StatusText
+
+	// Stats are the statistics of the attempts of Result.
+	Stats Stats
+
+	// Notice is the notice of Result, or the error of its
+	// first failed attempt, and Message is its message.
+	Notice  string
+	Message string
+
+	// Since is when the outage began, and Duration is how
+	// long it has lasted, or lasted if it is over.
+	Since    time.Time
+	Duration time.Duration
+
+	// Node is the checkup instance that checked Result,
+	// if configured.
+	Node string
+}
+
+// renderedMessage is a Message along with its title and
+// body, as rendered with the templates of a notifier. It is
+// what the templates of whole messages, such as the payload
+// of a Webhook, are executed with.
+type renderedMessage struct {
+	Message
+	Title string
+	Body  string
+}
+
+// newMessage returns the message about t.
+func newMessage(t Transition) Message {
+	m := Message{
+		Kind:     t.Kind,
+		Result:   t.Result,
+		Status:   t.Result.Status(),
+		Previous: Healthy,
+		Stats:    t.Result.ComputeStats(),
+		Notice:   resultNotice(t.Result),
+		Message:  t.Result.Message,
+		Since:    t.Since().Local(),
+		Duration: t.Duration().Round(time.Second),
+		Node:     t.Result.Node,
+	}
+	if t.Previous != nil {
+		m.Previous = t.Previous.Status
+	}
+	return m
+}
+
+// render returns the title and body of the message about t,
+// as given by the templates of o.
+func (o NotifierOptions) render(t Transition) (title, body string, err error) {
+	m := newMessage(t)
+	title, err = executeTemplate("title_template", o.TitleTemplate, DefaultTitleTemplate, m)
+	if err != nil {
+		return "", "", err
+	}
+	body, err = executeTemplate("body_template", o.BodyTemplate, DefaultBodyTemplate, m)
+	if err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(title), body, nil
+}
+
+// validateTemplates returns an error if the templates of o
+// cannot be parsed, or executed with a sample message.
+func (o NotifierOptions) validateTemplates() error {
+	sample := sampleMessage()
+	if o.TitleTemplate != "" {
+		if _, err := executeTemplate("title_template", o.TitleTemplate, "", sample); err != nil {
+			return err
+		}
+	}
+	if o.BodyTemplate != "" {
+		if _, err := executeTemplate("body_template", o.BodyTemplate, "", sample); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sampleMessage returns a message with which to check that
+// templates can be executed.
+func sampleMessage() Message {
+	return Message{
+		Kind:     TransitionFailing,
+		Result:   Result{Title: "Example", Endpoint: "https://example.com", Timestamp: Timestamp(), Down: true},
+		Status:   Down,
+		Previous: Healthy,
+		Since:    time.Now(),
+	}
+}
+
+// templateFuncs are the functions available to the
+// templates of notifiers, in addition to the predefined ones
+// of text/template:
+//
+//	json    formats its argument as JSON
+var templateFuncs = template.FuncMap{
+	"json": func(v interface{}) (string, error) {
+		b, err := json.Marshal(v)
+		return string(b), err
+	},
+}
+
+// executeTemplate executes the template text, or def if
+// text is empty, with data.
+func executeTemplate(name, text, def string, data interface{}) (string, error) {
+	if text == "" {
+		text = def
+	}
+	t, err := template.New(name).Option("missingkey=error").Funcs(templateFuncs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
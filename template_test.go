@@ -0,0 +1,74 @@
+package checkup
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRender(t *testing.T) {
+	since := time.Now().Add(-time.Hour)
+	result := Result{Title: "Web", Endpoint: "https://example.com", Timestamp: since.Add(90 * time.Minute).UnixNano(), Node: "paris"}
+	down, healthy := result, result
+	down.Down, down.Notice = true, "connection refused"
+	healthy.Healthy = true
+
+	for i, test := range []struct {
+		opts        NotifierOptions
+		t           Transition
+		title, body string
+	}{
+		{
+			t:     Transition{Kind: TransitionFailing, Result: down, Alert: &Alert{Status: Down, Since: down.Timestamp}},
+			title: "Web is down",
+			body:  "https://example.com is down: connection refused.\nChecked by paris.",
+		},
+		{
+			t:     Transition{Kind: TransitionWorsened, Result: down, Previous: &Alert{Status: Degraded, Since: since.UnixNano()}, Alert: &Alert{Status: Down, Since: since.UnixNano()}},
+			title: "Web went from degraded to down",
+			body:  "https://example.com is down: connection refused.\nIt has been failing for 1h30m0s",
+		},
+		{
+			t:     Transition{Kind: TransitionRecovered, Result: healthy, Previous: &Alert{Status: Down, Since: since.UnixNano()}},
+			title: "Web recovered",
+			body:  "https://example.com is healthy.\nIt was failing for 1h30m0s",
+		},
+		{
+			opts: NotifierOptions{
+				TitleTemplate: `[{{.Status}}] {{.Result.Title}} (was {{.Previous}})`,
+				BodyTemplate:  `{{.Notice}} after {{.Stats.Failures}} failures`,
+			},
+			t:     Transition{Kind: TransitionWorsened, Result: down, Previous: &Alert{Status: Degraded, Since: since.UnixNano()}, Alert: &Alert{Status: Down, Since: since.UnixNano()}},
+			title: "[down] Web (was degraded)",
+			body:  "connection refused after 0 failures",
+		},
+	} {
+		title, body, err := test.opts.render(test.t)
+		if err != nil {
+			t.Fatalf("Test %d: %v", i, err)
+		}
+		if title != test.title {
+			t.Errorf("Test %d: expected title %q, got %q", i, test.title, title)
+		}
+		if !strings.HasPrefix(body, test.body) {
+			t.Errorf("Test %d: expected body to start with %q, got %q", i, test.body, body)
+		}
+	}
+}
+
+func TestTemplatesValidated(t *testing.T) {
+	for _, config := range []string{
+		`{"notifier":{"name":"slack","title_template":"{{.Result.Title"}}`,
+		`{"notifier":{"name":"slack","body_template":"{{.Reslt.Title}}"}}`,
+	} {
+		var c Checkup
+		if err := json.Unmarshal([]byte(config), &c); err == nil {
+			t.Errorf("Expected an error for %s", config)
+		}
+	}
+	var c Checkup
+	if err := json.Unmarshal([]byte(`{"notifier":{"name":"slack","title_template":"{{.Result.Title}} is {{.Status}}"}}`), &c); err != nil {
+		t.Errorf("Expected a valid template, got %v", err)
+	}
+}
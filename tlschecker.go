@@ -1,15 +1,19 @@
 package checkup
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
 	"io/ioutil"
 	"net"
-	"os"
 	"time"
 )
 
+func init() {
+	RegisterChecker("tls", func() Checker { return TLSChecker{} })
+}
+
 // TLSChecker implements a Checker for TLS endpoints.
 //
 // TODO: Implement more checks on the certificate and TLS configuration.
@@ -56,24 +60,46 @@ type TLSChecker struct {
 	// fields, where necessary.
 	tlsConfig *tls.Config
 
-	// IgnoreTimes times when down check result should be ignored
-	// because of recurring maintenance for example
+	// IgnoreTimes are times of day ("15:04:05"), in the checker's
+	// Timezone, at which recurring maintenance starts. Results
+	// of checks during maintenance have the Maintenance status.
+	//
+	// Deprecated: use a MaintenanceWindow with At and Duration.
 	IgnoreTimes []string `json:"ignore_times,omitempty"`
 
-	// IgnoreDuration duration when down check result should be ignored
-	// because of recurring maintenance for example
+	// IgnoreDuration is how long maintenance lasts after each
+	// of IgnoreTimes.
+	//
+	// Deprecated: use a MaintenanceWindow with At and Duration.
 	IgnoreDuration time.Duration `json:"ignore_duration,omitempty"`
+
+	// CheckerOptions are the settings common to all checkers.
+	CheckerOptions
+}
+
+// Title returns the endpoint name of c.
+func (c TLSChecker) Title() string {
+	return c.Name
 }
 
 // Check performs checks using c according to its configuration.
 // An error is only returned if there is a configuration error.
 func (c TLSChecker) Check() (Result, error) {
+	return c.CheckContext(context.Background())
+}
+
+// CheckContext performs checks like Check, aborting pending
+// handshakes and attempts once ctx is done.
+func (c TLSChecker) CheckContext(ctx context.Context) (Result, error) {
 	if c.Attempts < 1 {
 		c.Attempts = 1
 	}
 	if c.CertExpiryThreshold == 0 {
 		c.CertExpiryThreshold = 24 * time.Hour * 14
 	}
+	if err := c.validate(); err != nil {
+		return Result{}, err
+	}
 
 	if len(c.TrustedRoots) > 0 {
 		if c.tlsConfig == nil {
@@ -93,7 +119,7 @@ func (c TLSChecker) Check() (Result, error) {
 		}
 	}
 
-	attempts, conns := c.doChecks()
+	attempts, conns := c.doChecks(ctx)
 
 	result := Result{
 		Title:        c.Name,
@@ -111,23 +137,99 @@ func (c TLSChecker) Check() (Result, error) {
 // will be open, so it's vital that conclude() is called,
 // passing in the connections, so that they will be inspected
 // and closed properly.
-func (c TLSChecker) doChecks() (Attempts, []*tls.Conn) {
+func (c TLSChecker) doChecks(ctx context.Context) (Attempts, []*tls.Conn) {
 	checks := make(Attempts, c.Attempts)
 	conns := make([]*tls.Conn, c.Attempts)
 	for i := 0; i < c.Attempts; i++ {
+		if err := ctx.Err(); err != nil {
+			checks[i].Error = attemptError(ctx, err)
+			continue
+		}
 		dialer := &net.Dialer{Timeout: c.Timeout}
 		start := time.Now()
-		conn, err := tls.DialWithDialer(dialer, "tcp", c.URL, c.tlsConfig)
+		conn, err := dialTLSContext(ctx, dialer, c.URL, c.tlsConfig)
 		checks[i].RTT = time.Since(start)
 		conns[i] = conn
 		if err != nil {
-			checks[i].Error = err.Error()
+			checks[i].Error = attemptError(ctx, err)
 			continue
 		}
 	}
 	return checks, conns
 }
 
+// certificateDetails records the details of the leaf
+// certificate presented in state into details, with their
+// keys prefixed by prefix.
+func certificateDetails(details map[string]interface{}, prefix string, state tls.ConnectionState) {
+	if len(state.PeerCertificates) == 0 {
+		return
+	}
+	leaf := state.PeerCertificates[0]
+	details[prefix+"subject"] = leaf.Subject.String()
+	details[prefix+"issuer"] = leaf.Issuer.String()
+	details[prefix+"not_after"] = formatTime(leaf.NotAfter)
+	if len(leaf.DNSNames) > 0 {
+		details[prefix+"dns_names"] = leaf.DNSNames
+	}
+}
+
+// dialTLSContext connects to addr like tls.DialWithDialer,
+// except that both the dial and the handshake are aborted
+// once ctx is done.
+func dialTLSContext(ctx context.Context, dialer *net.Dialer, addr string, config *tls.Config) (*tls.Conn, error) {
+	if dialer.Timeout != 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, dialer.Timeout)
+		defer cancel()
+	}
+
+	rawConn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	if config == nil {
+		config = new(tls.Config)
+	}
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		config = config.Clone()
+		config.ServerName = host
+	}
+
+	// abort the handshake if ctx is done before it completes,
+	// and wait for the watcher to stop so that it never sets a
+	// deadline on the conn once returned
+	done, stopped := make(chan struct{}), make(chan struct{})
+	go func() {
+		defer close(stopped)
+		select {
+		case <-ctx.Done():
+			rawConn.SetDeadline(time.Now())
+		case <-done:
+		}
+	}()
+
+	conn := tls.Client(rawConn, config)
+	err = conn.Handshake()
+	close(done)
+	<-stopped
+	if err != nil {
+		rawConn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	// the watcher may have fired just as the handshake ended
+	rawConn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
 // conclude takes the data in result from the attempts and
 // computes remaining values needed to fill out the result.
 // It detects less-than-ideal (degraded) connections and
@@ -144,32 +246,36 @@ func (c TLSChecker) conclude(conns []*tls.Conn, result Result) Result {
 	}()
 
 	if len(c.IgnoreTimes) > 0 && c.IgnoreDuration > 0 {
-		timeZone := os.Getenv("TZ")
-		if timeZone == "" {
-			timeZone = "Europe/Paris"
-		}
-		location, _ := time.LoadLocation(timeZone)
-		now := time.Now().UTC().In(location)
+		location, _ := c.ignoreLocation()
+		now := time.Now().In(location)
 		for i := range c.IgnoreTimes {
 			start, _ := time.ParseInLocation("15:04:05", c.IgnoreTimes[i], location)
 			start = start.AddDate(now.Year(), int(now.Month())-1, now.Day()-1)
 			start = start.In(location)
 			end := start.Add(c.IgnoreDuration)
 			if now.After(start) && now.Before(end) {
-				result.Healthy = true
-				return result
+				result.Maintenance = true
+				break
 			}
 		}
 	}
 
-	// check errors (down)
-	for i := range result.Times {
-		if result.Times[i].Error != "" {
-			result.Down = true
-			return result
+	for _, conn := range conns {
+		if conn != nil {
+			result.Details = make(map[string]interface{})
+			certificateDetails(result.Details, "", conn.ConnectionState())
+			break
 		}
 	}
 
+	stats := result.ComputeStats()
+
+	// check errors (down)
+	if c.down(stats) {
+		result.Down = true
+		return result
+	}
+
 	// check if certificates expired (down)
 	for i, conn := range conns {
 		if conn == nil {
@@ -204,13 +310,10 @@ func (c TLSChecker) conclude(conns []*tls.Conn, result Result) Result {
 	}
 
 	// check round trip time (degraded)
-	if c.ThresholdRTT > 0 {
-		stats := result.ComputeStats()
-		if stats.Median > c.ThresholdRTT {
-			result.Notice = fmt.Sprintf("median round trip time exceeded threshold (%s)", c.ThresholdRTT)
-			result.Degraded = true
-			return result
-		}
+	if degraded, notice := c.degraded(stats, c.ThresholdRTT); degraded {
+		result.Notice = notice
+		result.Degraded = true
+		return result
 	}
 
 	result.Healthy = true
@@ -73,6 +73,9 @@ func TestTLSChecker(t *testing.T) {
 	if got, want := len(result.Times), tc.Attempts; got != want {
 		t.Errorf("Expected %d attempts, got %d", want, got)
 	}
+	if got, want := result.Details["not_after"], formatTime(selfSigned.Leaf.NotAfter); got != want {
+		t.Errorf("Expected not_after detail %v, got %v", want, got)
+	}
 	ts := time.Unix(0, result.Timestamp)
 	if time.Since(ts) > 5*time.Second {
 		t.Errorf("Expected timestamp to be recent, got %s", ts)
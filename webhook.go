@@ -0,0 +1,263 @@
+package checkup
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/drone/envsubst"
+)
+
+func init() {
+	RegisterNotifier("webhook", func() Notifier { return Webhook{} })
+}
+
+// DefaultWebhookTimeout is how long a Webhook waits for a
+// response to each request, if not configured.
+const DefaultWebhookTimeout = 10 * time.Second
+
+// DefaultSignatureHeader is the header in which a Webhook
+// sends the signature of its requests, if not configured.
+const DefaultSignatureHeader = "X-Checkup-Signature"
+
+// Webhook is a notifier that sends a JSON request to a URL
+// about each transition of an endpoint, such as to an alert
+// intake service.
+type Webhook struct {
+	// URL is where requests are sent.
+	URL string `json:"url"`
+
+	// Method is the method of requests. Default is POST.
+	Method string `json:"method,omitempty"`
+
+	// Headers are added to requests. Environment variables
+	// in their values, such as ${TOKEN}, are expanded.
+	Headers http.Header `json:"headers,omitempty"`
+
+	// PayloadTemplate is the text/template template of the
+	// JSON body of requests, executed with a Message along
+	// with its rendered .Title and .Body. Default is a
+	// WebhookPayload.
+	PayloadTemplate string `json:"payload_template,omitempty"`
+
+	// Secret, if set, is the key with which the body of
+	// requests is signed with HMAC-SHA256. The signature is
+	// sent as "sha256=" followed by its hex encoding, in
+	// SignatureHeader (default DefaultSignatureHeader).
+	// Environment variables in Secret are expanded.
+	Secret          string `json:"secret,omitempty"`
+	SignatureHeader string `json:"signature_header,omitempty"`
+
+	// Retries is how many times a request that failed is
+	// sent again, waiting RetryBackoff (default 1s) before
+	// the first retry and twice as long before each next
+	// one. Requests are retried when they can't be sent or
+	// time out, and when they get a 5xx or 429 response.
+	// Retries are given up once the run of checkup is over,
+	// such as when checkup every is stopped.
+	Retries      int           `json:"retries,omitempty"`
+	RetryBackoff time.Duration `json:"retry_backoff,omitempty"`
+
+	// Timeout is how long to wait for the response to each
+	// request. Default is DefaultWebhookTimeout.
+	Timeout time.Duration `json:"timeout,omitempty"`
+
+	// Client is the http.Client with which to send
+	// requests. If not set, http.DefaultClient is used.
+	Client *http.Client `json:"-"`
+
+	NotifierOptions
+}
+
+// WebhookPayload is the default JSON body of the requests of
+// a Webhook.
+type WebhookPayload struct {
+	Kind     TransitionKind `json:"kind"`
+	Title    string         `json:"title"`
+	Body     string         `json:"body"`
+	Status   StatusText     `json:"status"`
+	Previous StatusText     `json:"previous"`
+
+	// Since is when the outage began, and Duration is how
+	// long it has lasted, as in "1h30m0s".
+	Since    time.Time `json:"since"`
+	Duration string    `json:"duration"`
+
+	Node   string `json:"node,omitempty"`
+	Result Result `json:"result"`
+}
+
+// Notify implements Notifier, keeping the alerts of w in
+// memory.
+func (w Webhook) Notify(results []Result) error {
+	return w.NotifyState(results, defaultNotifierState)
+}
+
+// NotifyState implements StatefulNotifier. Like Slack, it
+// sends a request about each endpoint that starts failing,
+// goes from degraded to down, is due a reminder or recovers.
+func (w Webhook) NotifyState(results []Result, state NotifierState) error {
+	return w.NotifyContext(context.Background(), results, state)
+}
+
+// NotifyContext implements ContextNotifier. It is like
+// NotifyState, but gives up retrying once ctx is done.
+func (w Webhook) NotifyContext(ctx context.Context, results []Result, state NotifierState) error {
+	key := notifierKey("webhook", w.method(), w.URL)
+	return w.notify(ctx, state, key, results, func(t Transition) error {
+		return w.sendTransition(ctx, t)
+	})
+}
+
+// method returns the method of the requests of w.
+func (w Webhook) method() string {
+	if w.Method == "" {
+		return http.MethodPost
+	}
+	return strings.ToUpper(w.Method)
+}
+
+// sendTransition sends a request about t.
+func (w Webhook) sendTransition(ctx context.Context, t Transition) error {
+	title, body, err := w.render(t)
+	if err != nil {
+		return fmt.Errorf("webhook: %v", err)
+	}
+	payload, err := w.payload(renderedMessage{newMessage(t), title, body})
+	if err != nil {
+		return fmt.Errorf("webhook: %v", err)
+	}
+	log.Printf("webhook: sending %s about %s", t.Kind, t.Result.Title)
+	return w.send(ctx, payload)
+}
+
+// payload returns the body of the request about m.
+func (w Webhook) payload(m renderedMessage) ([]byte, error) {
+	if w.PayloadTemplate == "" {
+		return json.Marshal(WebhookPayload{
+			Kind:     m.Kind,
+			Title:    m.Title,
+			Body:     m.Body,
+			Status:   m.Status,
+			Previous: m.Previous,
+			Since:    m.Since,
+			Duration: m.Duration.String(),
+			Node:     m.Node,
+			Result:   m.Result,
+		})
+	}
+	s, err := executeTemplate("payload_template", w.PayloadTemplate, "", m)
+	if err != nil {
+		return nil, err
+	}
+	if !json.Valid([]byte(s)) {
+		return nil, fmt.Errorf("payload_template: not valid JSON: %s", s)
+	}
+	return []byte(s), nil
+}
+
+// send sends body to w.URL, retrying as configured until
+// ctx is done.
+func (w Webhook) send(ctx context.Context, body []byte) error {
+	backoff := w.RetryBackoff
+	if backoff <= 0 {
+		backoff = time.Second
+	}
+	var err error
+	for attempt := 0; attempt <= w.Retries; attempt++ {
+		if attempt > 0 {
+			sleep(ctx, backoff)
+			if ctx.Err() != nil {
+				break
+			}
+			backoff *= 2
+		}
+		var retry bool
+		retry, err = w.do(ctx, body)
+		if err == nil || !retry {
+			break
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("webhook: %v", err)
+	}
+	return nil
+}
+
+// do sends a single request with body, and returns whether
+// it is worth retrying if it failed.
+func (w Webhook) do(ctx context.Context, body []byte) (bool, error) {
+	timeout := w.Timeout
+	if timeout <= 0 {
+		timeout = DefaultWebhookTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	method := w.method()
+	req, err := http.NewRequest(method, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for key, header := range w.Headers {
+		value, _ := envsubst.EvalEnv(strings.Join(header, ", "))
+		req.Header.Set(key, value)
+	}
+	if w.Secret != "" {
+		secret, _ := envsubst.EvalEnv(w.Secret)
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		header := w.SignatureHeader
+		if header == "" {
+			header = DefaultSignatureHeader
+		}
+		req.Header.Set(header, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := w.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, 1<<16))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		retry := resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests
+		return retry, fmt.Errorf("%s %s: %s", method, w.URL, resp.Status)
+	}
+	return false, nil
+}
+
+// validate returns an error if w is misconfigured.
+func (w Webhook) validate() error {
+	if w.URL == "" {
+		return fmt.Errorf("webhook: no url configured")
+	}
+	if w.Retries < 0 {
+		return fmt.Errorf("webhook: invalid retries: %d (must be >= 0)", w.Retries)
+	}
+	if err := w.NotifierOptions.validate(); err != nil {
+		return fmt.Errorf("webhook: %v", err)
+	}
+	if w.PayloadTemplate != "" {
+		if _, err := w.payload(renderedMessage{Message: sampleMessage()}); err != nil {
+			return fmt.Errorf("webhook: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package checkup
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWebhook(t *testing.T) {
+	os.Setenv("CHECKUP_TEST_TOKEN", "s3cr3t")
+	defer os.Unsetenv("CHECKUP_TEST_TOKEN")
+
+	var mu sync.Mutex
+	var requests []*http.Request
+	var bodies [][]byte
+	fail := 1
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		if fail > 0 {
+			fail--
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		b, _ := ioutil.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, b)
+	}))
+	defer srv.Close()
+
+	hook := Webhook{
+		URL:          srv.URL,
+		Method:       http.MethodPut,
+		Headers:      http.Header{"Authorization": {"Bearer ${CHECKUP_TEST_TOKEN}"}},
+		Secret:       "key",
+		Retries:      1,
+		RetryBackoff: time.Millisecond,
+	}
+	state := &memoryNotifierState{alerts: make(map[string]Alert)}
+	down := Result{Title: "Web", Endpoint: srv.URL, Timestamp: Timestamp(), Down: true}
+	for i := 0; i < 2; i++ {
+		if err := hook.NotifyState([]Result{down}, state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(requests) != 1 {
+		t.Fatalf("Expected a single request once retried, got %d", len(requests))
+	}
+	r := requests[0]
+	if r.Method != http.MethodPut || r.Header.Get("Authorization") != "Bearer s3cr3t" || r.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("Unexpected request: %s %v", r.Method, r.Header)
+	}
+	mac := hmac.New(sha256.New, []byte("key"))
+	mac.Write(bodies[0])
+	if got, want := r.Header.Get(DefaultSignatureHeader), "sha256="+hex.EncodeToString(mac.Sum(nil)); got != want {
+		t.Errorf("Expected signature %s, got %s", want, got)
+	}
+	var payload WebhookPayload
+	if err := json.Unmarshal(bodies[0], &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Kind != TransitionFailing || payload.Title != "Web is down" || payload.Status != Down || payload.Result.Title != "Web" {
+		t.Errorf("Unexpected payload: %s", bodies[0])
+	}
+
+	hook.PayloadTemplate = `{"text": {{json .Title}}, "was": {{json .Previous}}}`
+	healthy := Result{Title: "Web", Endpoint: srv.URL, Timestamp: Timestamp(), Healthy: true}
+	if err := hook.NotifyState([]Result{healthy}, state); err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 2 || string(bodies[1]) != `{"text": "Web recovered", "was": "down"}` {
+		t.Errorf("Expected the recovery with the payload template, got %q", bodies[len(bodies)-1])
+	}
+
+	mu.Lock()
+	fail = 5
+	mu.Unlock()
+	if err := hook.NotifyState([]Result{down}, state); err == nil {
+		t.Error("Expected an error once retries are exhausted")
+	}
+}
+
+func TestWebhookContext(t *testing.T) {
+	var mu sync.Mutex
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		http.Error(w, "busy", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	hook := Webhook{URL: srv.URL, Retries: 3, RetryBackoff: time.Hour}
+	state := &memoryNotifierState{alerts: make(map[string]Alert)}
+	down := Result{Title: "Web", Endpoint: srv.URL, Timestamp: Timestamp(), Down: true}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if err := hook.NotifyContext(ctx, []Result{down}, state); err == nil {
+		t.Error("Expected an error once the context is done")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected retries to be given up once the context is done, took %s", elapsed)
+	}
+	if requests != 1 {
+		t.Errorf("Expected a single request, got %d", requests)
+	}
+
+	// the default method is the same as POST
+	if a, b := (Webhook{URL: srv.URL}), (Webhook{URL: srv.URL, Method: "post"}); a.method() != b.method() {
+		t.Errorf("Expected the same method, got %q and %q", a.method(), b.method())
+	}
+}
+
+func TestWebhookValidate(t *testing.T) {
+	for _, config := range []string{
+		`{"notifier":{"name":"webhook"}}`,
+		`{"notifier":{"name":"webhook","url":"https://example.com","payload_template":"{{.Title}}"}}`,
+		`{"notifier":{"name":"webhook","url":"https://example.com","title_template":"{{.Nope}}"}}`,
+	} {
+		var c Checkup
+		if err := json.Unmarshal([]byte(config), &c); err == nil {
+			t.Errorf("Expected an error for %s", config)
+		}
+	}
+	var c Checkup
+	config := `{"notifier":{"name":"webhook","url":"https://example.com","payload_template":"{\"text\": {{json .Title}}}"}}`
+	if err := json.Unmarshal([]byte(config), &c); err != nil {
+		t.Errorf("Expected a valid webhook, got %v", err)
+	}
+}